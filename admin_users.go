@@ -0,0 +1,427 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/UUest/gohttp/internal/database"
+)
+
+// adminUserParameters is the JSON shape of a user in the admin API.
+type adminUserParameters struct {
+	Id                      uuid.UUID  `json:"id"`
+	Created_at              time.Time  `json:"created_at"`
+	Updated_at              time.Time  `json:"updated_at"`
+	Email                   string     `json:"email"`
+	Email_verified_at       *time.Time `json:"email_verified_at,omitempty"`
+	Handle                  string     `json:"handle,omitempty"`
+	Role                    string     `json:"role"`
+	Is_chirpy_red           bool       `json:"is_chirpy_red"`
+	Suspended_at            *time.Time `json:"suspended_at,omitempty"`
+	Suspended_until         *time.Time `json:"suspended_until,omitempty"`
+	Suspension_reason       string     `json:"suspension_reason,omitempty"`
+	Password_reset_required bool       `json:"password_reset_required"`
+	Two_factor_enabled      bool       `json:"two_factor_enabled"`
+}
+
+func adminUserResponse(user database.User) adminUserParameters {
+	return adminUserParameters{
+		Id:                      user.ID,
+		Created_at:              user.CreatedAt,
+		Updated_at:              user.UpdatedAt,
+		Email:                   user.Email,
+		Email_verified_at:       nullTimePtr(user.EmailVerifiedAt),
+		Handle:                  user.Handle.String,
+		Role:                    user.Role,
+		Is_chirpy_red:           user.ChirpyRed.Bool,
+		Suspended_at:            nullTimePtr(user.SuspendedAt),
+		Suspended_until:         nullTimePtr(user.SuspendedUntil),
+		Suspension_reason:       user.SuspensionReason,
+		Password_reset_required: user.PasswordResetRequired,
+		Two_factor_enabled:      user.TotpEnabledAt.Valid,
+	}
+}
+
+// likePattern escapes the LIKE wildcards in s so it matches literally.
+var likePattern = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// listUsers lists accounts newest first. ?q= narrows the list to users
+// whose email or handle contains it.
+func (cfg *apiConfig) listUsers(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePageRequest(r)
+	if err != nil {
+		log.Printf("failed to parse page request: %s", err)
+		respondWithError(w, http.StatusBadRequest, []byte("invalid limit or cursor"))
+		return
+	}
+	query := sql.NullString{}
+	if q := strings.TrimSpace(r.URL.Query().Get("q")); q != "" {
+		query = sql.NullString{String: likePattern.Replace(q), Valid: true}
+	}
+	users, err := cfg.dbQueries.ListUsers(r.Context(), database.ListUsersParams{
+		Query:           query,
+		CursorCreatedAt: page.CursorCreatedAt,
+		CursorID:        page.CursorID,
+		PageSize:        int32(page.Limit + 1),
+	})
+	if err != nil {
+		log.Printf("failed to list users: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	users, hasMore := trimPage(users, page.Limit)
+	resUsers := make([]adminUserParameters, 0, len(users))
+	for _, user := range users {
+		resUsers = append(resUsers, adminUserResponse(user))
+	}
+	nextCursor := ""
+	if hasMore && len(users) > 0 {
+		last := users[len(users)-1]
+		nextCursor = encodeCursor(pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+		setNextLink(w, r, nextCursor, page.Limit)
+	}
+	type resParameters struct {
+		Users      []adminUserParameters `json:"users"`
+		NextCursor string                `json:"next_cursor,omitempty"`
+	}
+	dat, err := json.Marshal(resParameters{
+		Users:      resUsers,
+		NextCursor: nextCursor,
+	})
+	if err != nil {
+		log.Printf("failed to marshal response body: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, dat)
+}
+
+// adminTargetUser parses the {userID} path value and loads that user. It
+// writes the error response and returns false when the user can't be
+// loaded.
+func (cfg *apiConfig) adminTargetUser(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	userUUID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, nil)
+		return database.User{}, false
+	}
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), userUUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, nil)
+		return database.User{}, false
+	}
+	if err != nil {
+		log.Printf("failed to get user by id: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return database.User{}, false
+	}
+	return user, true
+}
+
+func respondWithAdminUser(w http.ResponseWriter, user database.User) {
+	dat, err := json.Marshal(adminUserResponse(user))
+	if err != nil {
+		log.Printf("failed to marshal response body: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, dat)
+}
+
+func (cfg *apiConfig) getUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	respondWithAdminUser(w, user)
+}
+
+// getUserChirps lists every chirp a user wrote, newest first, including
+// chirps hidden by moderators.
+func (cfg *apiConfig) getUserChirps(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	page, err := parsePageRequest(r)
+	if err != nil {
+		log.Printf("failed to parse page request: %s", err)
+		respondWithError(w, http.StatusBadRequest, []byte("invalid limit or cursor"))
+		return
+	}
+	chirps, err := cfg.dbQueries.ListUserChirps(r.Context(), database.ListUserChirpsParams{
+		UserID:          user.ID,
+		CursorCreatedAt: page.CursorCreatedAt,
+		CursorID:        page.CursorID,
+		PageSize:        int32(page.Limit + 1),
+	})
+	if err != nil {
+		log.Printf("failed to list user chirps: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	chirps, hasMore := trimPage(chirps, page.Limit)
+	type chirpParameters struct {
+		Id          uuid.UUID  `json:"id"`
+		Body        string     `json:"body"`
+		Created_at  time.Time  `json:"created_at"`
+		Updated_at  time.Time  `json:"updated_at"`
+		In_reply_to *uuid.UUID `json:"in_reply_to,omitempty"`
+		Quote_of    *uuid.UUID `json:"quote_of,omitempty"`
+		Hidden_at   *time.Time `json:"hidden_at,omitempty"`
+	}
+	resChirps := make([]chirpParameters, 0, len(chirps))
+	for _, chirp := range chirps {
+		resChirps = append(resChirps, chirpParameters{
+			Id:          chirp.ID,
+			Body:        chirp.Body,
+			Created_at:  chirp.CreatedAt,
+			Updated_at:  chirp.UpdatedAt,
+			In_reply_to: nullUUIDPtr(chirp.InReplyTo),
+			Quote_of:    nullUUIDPtr(chirp.QuoteOf),
+			Hidden_at:   nullTimePtr(chirp.HiddenAt),
+		})
+	}
+	nextCursor := ""
+	if hasMore && len(chirps) > 0 {
+		last := chirps[len(chirps)-1]
+		nextCursor = encodeCursor(pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+		setNextLink(w, r, nextCursor, page.Limit)
+	}
+	type resParameters struct {
+		Chirps     []chirpParameters `json:"chirps"`
+		NextCursor string            `json:"next_cursor,omitempty"`
+	}
+	dat, err := json.Marshal(resParameters{
+		Chirps:     resChirps,
+		NextCursor: nextCursor,
+	})
+	if err != nil {
+		log.Printf("failed to marshal response body: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, dat)
+}
+
+// getUserSessions lists a user's refresh tokens. Tokens are identified by
+// a fingerprint so the admin API never exposes usable credentials.
+func (cfg *apiConfig) getUserSessions(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	tokens, err := cfg.dbQueries.ListUserRefreshTokens(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("failed to list refresh tokens: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	type sessionParameters struct {
+		Fingerprint  string     `json:"fingerprint"`
+		Session_id   uuid.UUID  `json:"session_id"`
+		Created_at   time.Time  `json:"created_at"`
+		Last_used_at time.Time  `json:"last_used_at"`
+		Expires_at   time.Time  `json:"expires_at"`
+		Revoked_at   *time.Time `json:"revoked_at,omitempty"`
+		User_agent   string     `json:"user_agent"`
+		Ip           string     `json:"ip"`
+	}
+	sessions := make([]sessionParameters, 0, len(tokens))
+	for _, token := range tokens {
+		sessions = append(sessions, sessionParameters{
+			Fingerprint:  token.TokenHash[:12],
+			Session_id:   token.FamilyID,
+			Created_at:   token.CreatedAt,
+			Last_used_at: token.LastUsedAt,
+			Expires_at:   token.ExpiresAt,
+			Revoked_at:   nullTimePtr(token.RevokedAt),
+			User_agent:   token.UserAgent,
+			Ip:           token.Ip,
+		})
+	}
+	dat, err := json.Marshal(sessions)
+	if err != nil {
+		log.Printf("failed to marshal response body: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, dat)
+}
+
+// suspendUser locks an account out: its access tokens stop working and its
+// refresh tokens are revoked. The body may give a reason and a duration
+// such as "72h"; without a duration the account is banned until it is
+// unsuspended.
+func (cfg *apiConfig) suspendUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	if claims, ok := claimsFromContext(r.Context()); ok && claims.UserID() == user.ID {
+		respondWithError(w, http.StatusBadRequest, []byte("cannot suspend yourself"))
+		return
+	}
+	type reqParameters struct {
+		Reason   string `json:"reason"`
+		Duration string `json:"duration"`
+	}
+	reqParams := reqParameters{}
+	if r.ContentLength != 0 {
+		err := json.NewDecoder(r.Body).Decode(&reqParams)
+		if err != nil {
+			log.Printf("failed to decode request body: %s", err)
+			respondWithError(w, http.StatusBadRequest, nil)
+			return
+		}
+	}
+	duration, err := parseSuspensionDuration(reqParams.Duration)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, []byte(err.Error()))
+		return
+	}
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("failed to begin transaction: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+	_, err = qtx.SuspendUser(r.Context(), database.SuspendUserParams{
+		Reason:          strings.TrimSpace(reqParams.Reason),
+		DurationSeconds: duration,
+		ID:              user.ID,
+	})
+	if err != nil {
+		log.Printf("failed to suspend user: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	err = qtx.RevokeUserRefreshTokens(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("failed to revoke refresh tokens: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	user, err = qtx.GetUserByID(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("failed to get user by id: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("failed to commit suspension: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	cfg.accountCache.Delete(user.ID)
+	respondWithAdminUser(w, user)
+}
+
+func (cfg *apiConfig) unsuspendUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	_, err := cfg.dbQueries.UnsuspendUser(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("failed to unsuspend user: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	cfg.accountCache.Delete(user.ID)
+	user, err = cfg.dbQueries.GetUserByID(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("failed to get user by id: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	respondWithAdminUser(w, user)
+}
+
+// forcePasswordReset signs the user out everywhere and, until they choose
+// a new password through PUT /api/users, limits their access tokens to
+// that endpoint.
+func (cfg *apiConfig) forcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("failed to begin transaction: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+	_, err = qtx.RequirePasswordReset(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("failed to require password reset: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	err = qtx.RevokeUserRefreshTokens(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("failed to revoke refresh tokens: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	user, err = qtx.GetUserByID(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("failed to get user by id: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("failed to commit password reset: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	cfg.accountCache.Delete(user.ID)
+	respondWithAdminUser(w, user)
+}
+
+// setUserChirpyRed grants or removes Chirpy Red by hand, for cases the
+// Polka webhook doesn't cover.
+func (cfg *apiConfig) setUserChirpyRed(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	type reqParameters struct {
+		Is_chirpy_red *bool `json:"is_chirpy_red"`
+	}
+	reqParams := reqParameters{}
+	err := json.NewDecoder(r.Body).Decode(&reqParams)
+	if err != nil || reqParams.Is_chirpy_red == nil {
+		respondWithError(w, http.StatusBadRequest, []byte("is_chirpy_red is required"))
+		return
+	}
+	_, err = cfg.dbQueries.UpdateUserChirpyRed(r.Context(), database.UpdateUserChirpyRedParams{
+		ChirpyRed: sql.NullBool{Bool: *reqParams.Is_chirpy_red, Valid: true},
+		ID:        user.ID,
+	})
+	if err != nil {
+		log.Printf("failed to update chirpy red: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	user, err = cfg.dbQueries.GetUserByID(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("failed to get user by id: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	respondWithAdminUser(w, user)
+}
@@ -1,20 +1,26 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
-	"regexp"
-	"sort"
 	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 
 	"github.com/UUest/gohttp/internal/auth"
+	"github.com/UUest/gohttp/internal/cache"
+	"github.com/UUest/gohttp/internal/chirptext"
 	"github.com/UUest/gohttp/internal/database"
+	"github.com/UUest/gohttp/internal/mail"
+	"github.com/UUest/gohttp/internal/moderation"
+	"github.com/UUest/gohttp/internal/ratelimit"
 )
 
 func readiness(w http.ResponseWriter, r *http.Request) {
@@ -35,29 +41,232 @@ func respondWithError(w http.ResponseWriter, status int, dat []byte) {
 	w.Write(dat)
 }
 
-func chirpCleaner(chirp string) (string, bool) {
-	profaneWords := []string{"kerfuffle", "sharbert", "fornax"}
-	replaced := false
+// isUniqueViolation reports whether err is a Postgres unique constraint
+// violation.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
 
-	for _, word := range profaneWords {
-		pattern := fmt.Sprintf(`(?i)\b%v\b`, regexp.QuoteMeta(word))
-		re := regexp.MustCompile(pattern)
+func nullUUIDPtr(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
 
-		if re.MatchString(chirp) {
-			replaced = true
-			chirp = re.ReplaceAllString(chirp, "****")
-		}
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
 	}
+	return &t.Time
+}
+
+const maxChirpLength = 140
+
+var (
+	errChirpTooLong  = errors.New("chirp is too long")
+	errChirpRejected = errors.New("chirp violates the moderation policy")
+)
 
-	return chirp, replaced
+// validateChirpBody enforces the length limit and runs the body through the
+// moderation dictionary. The result holds the masked body and any flag
+// rules that matched.
+func (cfg *apiConfig) validateChirpBody(body string) (moderation.Result, error) {
+	if len(body) > maxChirpLength {
+		return moderation.Result{}, errChirpTooLong
+	}
+	result := cfg.moderation.filter.Apply(body)
+	if result.Rejected {
+		return moderation.Result{}, errChirpRejected
+	}
+	return result, nil
 }
 
 type apiConfig struct {
 	fileserverHits atomic.Int32
+	db             *sql.DB
 	dbQueries      *database.Queries
 	platform       string
-	jwtSecret      string
+	jwtKeys        *auth.KeySet
 	polkaKey       string
+	moderation     *moderationSource
+	// accountCache holds recent account status lookups so authenticated
+	// requests don't each query Postgres.
+	accountCache *cache.Cache[uuid.UUID, database.GetUserAccountStatusRow]
+	rateLimiter  ratelimit.Store
+	mailer       mail.Mailer
+	// requireEmailVerification stops users from chirping until they
+	// verify their email address.
+	requireEmailVerification bool
+	// trustProxy makes clientIP read X-Forwarded-For.
+	trustProxy bool
+}
+
+// chirpResponse is the JSON shape of a chirp returned by the API.
+// Rechirps share the shape: their id, user_id and timestamps describe the
+// rechirp itself and the shared chirp is embedded in rechirp_of.
+type chirpResponse struct {
+	Id          uuid.UUID         `json:"id"`
+	Body        string            `json:"body"`
+	Created_at  time.Time         `json:"created_at"`
+	Updated_at  time.Time         `json:"updated_at"`
+	User_id     uuid.UUID         `json:"user_id"`
+	In_reply_to *uuid.UUID        `json:"in_reply_to,omitempty"`
+	Quote_of    *uuid.UUID        `json:"quote_of,omitempty"`
+	Quoted      *chirpResponse    `json:"quoted,omitempty"`
+	Rechirp_of  *chirpResponse    `json:"rechirp_of,omitempty"`
+	Mentions    []mentionResponse `json:"mentions,omitempty"`
+	Like_count  int64             `json:"like_count"`
+	Liked_by_me *bool             `json:"liked_by_me,omitempty"`
+}
+
+// chirpResponses converts chirps to their JSON shape, fills in like counts
+// and embeds quoted chirps. liked_by_me is only set when viewerID is valid.
+func (cfg *apiConfig) chirpResponses(ctx context.Context, chirps []database.Chirp, viewerID uuid.NullUUID) ([]chirpResponse, error) {
+	return cfg.buildChirpResponses(ctx, chirps, viewerID, true)
+}
+
+func (cfg *apiConfig) buildChirpResponses(ctx context.Context, chirps []database.Chirp, viewerID uuid.NullUUID, embedQuotes bool) ([]chirpResponse, error) {
+	chirpIDs := make([]uuid.UUID, 0, len(chirps))
+	var quotedIDs []uuid.UUID
+	for _, chirp := range chirps {
+		chirpIDs = append(chirpIDs, chirp.ID)
+		if chirp.QuoteOf.Valid {
+			quotedIDs = append(quotedIDs, chirp.QuoteOf.UUID)
+		}
+	}
+	likeCounts := make(map[uuid.UUID]int64, len(chirps))
+	likedByMe := make(map[uuid.UUID]bool)
+	mentions := make(map[uuid.UUID][]mentionResponse)
+	if len(chirpIDs) > 0 {
+		counts, err := cfg.dbQueries.GetChirpLikeCounts(ctx, chirpIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, count := range counts {
+			likeCounts[count.ChirpID] = count.LikeCount
+		}
+		mentionRows, err := cfg.dbQueries.GetChirpMentions(ctx, chirpIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range mentionRows {
+			mentions[row.ChirpID] = append(mentions[row.ChirpID], mentionResponse{
+				User_id: row.UserID,
+				Handle:  row.Handle,
+				Start:   row.StartIndex,
+				End:     row.EndIndex,
+			})
+		}
+		if viewerID.Valid {
+			liked, err := cfg.dbQueries.GetLikedChirpIDs(ctx, database.GetLikedChirpIDsParams{
+				UserID:   viewerID.UUID,
+				ChirpIds: chirpIDs,
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, id := range liked {
+				likedByMe[id] = true
+			}
+		}
+	}
+	// Quoted chirps are embedded one level deep; a quote of a quote only
+	// carries the quote_of ID.
+	quoted := make(map[uuid.UUID]chirpResponse)
+	if embedQuotes && len(quotedIDs) > 0 {
+		quotedChirps, err := cfg.dbQueries.GetChirpsByIDs(ctx, quotedIDs)
+		if err != nil {
+			return nil, err
+		}
+		quotedResponses, err := cfg.buildChirpResponses(ctx, quotedChirps, viewerID, false)
+		if err != nil {
+			return nil, err
+		}
+		for _, response := range quotedResponses {
+			quoted[response.Id] = response
+		}
+	}
+	responses := make([]chirpResponse, 0, len(chirps))
+	for _, chirp := range chirps {
+		response := chirpResponse{
+			Id:          chirp.ID,
+			Body:        chirp.Body,
+			Created_at:  chirp.CreatedAt,
+			Updated_at:  chirp.UpdatedAt,
+			User_id:     chirp.UserID,
+			In_reply_to: nullUUIDPtr(chirp.InReplyTo),
+			Quote_of:    nullUUIDPtr(chirp.QuoteOf),
+			Mentions:    mentions[chirp.ID],
+			Like_count:  likeCounts[chirp.ID],
+		}
+		if q, ok := quoted[chirp.QuoteOf.UUID]; ok && chirp.QuoteOf.Valid {
+			response.Quoted = &q
+		}
+		if viewerID.Valid {
+			liked := likedByMe[chirp.ID]
+			response.Liked_by_me = &liked
+		}
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+// trimPage drops the extra row fetched past limit and reports whether it
+// was there, i.e. whether a next page exists.
+func trimPage[T any](rows []T, limit int) ([]T, bool) {
+	if len(rows) > limit {
+		return rows[:limit], true
+	}
+	return rows, false
+}
+
+// respondWithChirpPage writes one page of chirps, with a next cursor taken
+// from the last chirp when hasMore is set.
+func respondWithChirpPage(w http.ResponseWriter, r *http.Request, chirps []chirpResponse, hasMore bool, limit int) {
+	nextCursor := ""
+	if hasMore && len(chirps) > 0 {
+		last := chirps[len(chirps)-1]
+		nextCursor = encodeCursor(pageCursor{CreatedAt: last.Created_at, ID: last.Id})
+	}
+	writeChirpPage(w, r, chirps, nextCursor, limit)
+}
+
+// writeChirpPage writes one page of chirps. An empty nextCursor marks the
+// last page.
+func writeChirpPage(w http.ResponseWriter, r *http.Request, chirps []chirpResponse, nextCursor string, limit int) {
+	if nextCursor != "" {
+		setNextLink(w, r, nextCursor, limit)
+	}
+	type resParameters struct {
+		Chirps     []chirpResponse `json:"chirps"`
+		NextCursor string          `json:"next_cursor,omitempty"`
+	}
+	dat, err := json.Marshal(resParameters{
+		Chirps:     chirps,
+		NextCursor: nextCursor,
+	})
+	if err != nil {
+		log.Printf("failed to marshal response body: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, dat)
+}
+
+// viewerID returns the caller's user ID when the request carries a valid
+// access token. Endpoints that work anonymously use it to personalize
+// their responses.
+func (cfg *apiConfig) viewerID(r *http.Request) uuid.NullUUID {
+	if r.Header.Get("Authorization") == "" {
+		return uuid.NullUUID{}
+	}
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: userID, Valid: true}
 }
 
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
@@ -88,16 +297,21 @@ func (cfg *apiConfig) resetMetrics(w http.ResponseWriter, r *http.Request) {
 }
 
 func (cfg *apiConfig) createChirp(w http.ResponseWriter, r *http.Request) {
-	token, err := auth.GetBearerToken(r.Header)
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	userID, err := cfg.authenticate(r)
 	if err != nil {
-		log.Printf("failed to validate JWTToken: %s", err)
-		respondWithError(w, http.StatusUnauthorized, nil)
+		respondWithAuthError(w, err)
+		return
+	}
+	err = cfg.checkEmailVerified(r.Context(), userID)
+	if err != nil {
+		respondWithAuthError(w, err)
 		return
 	}
 	type reqParameters struct {
-		Body   string    `json:"body"`
-		UserID uuid.UUID `json:"user_id"`
+		Body      string     `json:"body"`
+		UserID    uuid.UUID  `json:"user_id"`
+		InReplyTo *uuid.UUID `json:"in_reply_to"`
+		QuoteOf   *uuid.UUID `json:"quote_of"`
 	}
 	decoder := json.NewDecoder(r.Body)
 	reqParams := reqParameters{}
@@ -107,55 +321,109 @@ func (cfg *apiConfig) createChirp(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	moderated, err := cfg.validateChirpBody(reqParams.Body)
+	if errors.Is(err, errChirpRejected) {
+		respondWithError(w, http.StatusBadRequest, []byte("Chirp violates the moderation policy"))
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, []byte("Chirp is too long"))
+		return
+	}
 	chirpParams := database.CreateChirpParams{}
-	valid := true
-	if len(reqParams.Body) > 140 {
-		chirpParams.Body = "Chirp is too long"
-		valid = false
-	} else {
-		chirpParams.Body = reqParams.Body
+	if reqParams.InReplyTo != nil {
+		parent, err := cfg.dbQueries.GetVisibleChirpByID(r.Context(), *reqParams.InReplyTo)
+		if err != nil {
+			log.Printf("failed to get parent chirp: %s", err)
+			respondWithError(w, http.StatusBadRequest, []byte("in_reply_to chirp not found"))
+			return
+		}
+		blocked, err := cfg.dbQueries.IsBlockedBetween(r.Context(), database.IsBlockedBetweenParams{
+			UserA: userID,
+			UserB: parent.UserID,
+		})
+		if err != nil {
+			log.Printf("failed to check blocks: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if blocked {
+			respondWithError(w, http.StatusForbidden, []byte("cannot reply to this user"))
+			return
+		}
+		chirpParams.InReplyTo = uuid.NullUUID{UUID: parent.ID, Valid: true}
 	}
-	_, rep := chirpCleaner(chirpParams.Body)
-	if rep == true {
-		chirpParams.Body, _ = chirpCleaner(chirpParams.Body)
-	} else {
-		chirpParams.Body = reqParams.Body
+	if reqParams.QuoteOf != nil {
+		quoted, err := cfg.dbQueries.GetVisibleChirpByID(r.Context(), *reqParams.QuoteOf)
+		if err != nil {
+			log.Printf("failed to get quoted chirp: %s", err)
+			respondWithError(w, http.StatusBadRequest, []byte("quote_of chirp not found"))
+			return
+		}
+		chirpParams.QuoteOf = uuid.NullUUID{UUID: quoted.ID, Valid: true}
 	}
+	chirpParams.Body = moderated.Text
 	chirpParams.ID = uuid.New()
 	chirpParams.UserID = userID
 
-	newChirp, err := cfg.dbQueries.CreateChirp(r.Context(), chirpParams)
-	type resParameters struct {
-		Id         uuid.UUID `json:"id"`
-		Body       string    `json:"body"`
-		Created_at time.Time `json:"created_at"`
-		Updated_at time.Time `json:"updated_at"`
-		User_id    uuid.UUID `json:"user_id"`
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("failed to begin transaction: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
 	}
-	resParams := resParameters{
-		Id:         newChirp.ID,
-		Body:       newChirp.Body,
-		Created_at: newChirp.CreatedAt,
-		Updated_at: newChirp.UpdatedAt,
-		User_id:    newChirp.UserID,
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+	newChirp, err := qtx.CreateChirp(r.Context(), chirpParams)
+	if err != nil {
+		log.Printf("failed to create chirp: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
 	}
-	res, err := json.Marshal(resParams)
+	err = saveChirpHashtags(r.Context(), qtx, newChirp)
 	if err != nil {
-		log.Printf("failed to marshal response body: %s", err)
+		log.Printf("failed to save chirp hashtags: %s", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	if valid == false {
-		respondWithError(w, http.StatusBadRequest, res)
-	} else {
-		respondWithJSON(w, http.StatusCreated, res)
+	err = saveChirpMentions(r.Context(), qtx, newChirp)
+	if err != nil {
+		log.Printf("failed to save chirp mentions: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	err = saveChirpFlags(r.Context(), qtx, newChirp, moderated.Flagged)
+	if err != nil {
+		log.Printf("failed to save chirp flags: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("failed to commit chirp: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	resParams, err := cfg.chirpResponses(r.Context(), []database.Chirp{newChirp}, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		log.Printf("failed to build chirp response: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	res, err := json.Marshal(resParams[0])
+	if err != nil {
+		log.Printf("failed to marshal response body: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
 	}
+	respondWithJSON(w, http.StatusCreated, res)
 }
 
 func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
 	type reqParameters struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Handle   string `json:"handle"`
 	}
 	decoder := json.NewDecoder(r.Body)
 	reqParams := reqParameters{}
@@ -165,6 +433,19 @@ func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	if !mail.ValidAddress(reqParams.Email) {
+		respondWithError(w, http.StatusBadRequest, []byte("invalid email"))
+		return
+	}
+	handle := sql.NullString{}
+	if reqParams.Handle != "" {
+		handle.String = chirptext.NormalizeHandle(reqParams.Handle)
+		handle.Valid = true
+		if !chirptext.ValidHandle(handle.String) {
+			respondWithError(w, http.StatusBadRequest, []byte("invalid handle"))
+			return
+		}
+	}
 	hashedPassword, err := auth.HashPassword(reqParams.Password)
 	if err != nil {
 		log.Printf("failed to hash password: %s", err)
@@ -174,19 +455,30 @@ func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
 	userParams := database.CreateUserParams{
 		Email:          reqParams.Email,
 		HashedPassword: hashedPassword,
+		Handle:         handle,
 	}
 	newUser, err := cfg.dbQueries.CreateUser(r.Context(), userParams)
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, []byte("email or handle already taken"))
+		return
+	}
 	if err != nil {
 		log.Printf("failed to create user: %s", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	err = cfg.startEmailVerification(r.Context(), newUser.ID, newUser.Email)
+	if err != nil {
+		log.Printf("failed to start email verification: %s", err)
+	}
 	type resParameters struct {
-		Id          uuid.UUID `json:"id"`
-		Created_at  time.Time `json:"created_at"`
-		Updated_at  time.Time `json:"updated_at"`
-		Email       string    `json:"email"`
-		IsChirpyRed bool      `json:"is_chirpy_red"`
+		Id            uuid.UUID `json:"id"`
+		Created_at    time.Time `json:"created_at"`
+		Updated_at    time.Time `json:"updated_at"`
+		Email         string    `json:"email"`
+		IsChirpyRed   bool      `json:"is_chirpy_red"`
+		Handle        string    `json:"handle,omitempty"`
+		EmailVerified bool      `json:"email_verified"`
 	}
 	resParams := resParameters{
 		Id:          newUser.ID,
@@ -194,6 +486,7 @@ func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
 		Updated_at:  newUser.UpdatedAt,
 		Email:       newUser.Email,
 		IsChirpyRed: newUser.ChirpyRed.Bool,
+		Handle:      newUser.Handle.String,
 	}
 	dat, err := json.Marshal(resParams)
 	if err != nil {
@@ -220,76 +513,100 @@ func (cfg *apiConfig) deleteAllUsers(w http.ResponseWriter, r *http.Request) {
 }
 
 func (cfg *apiConfig) getChirps(w http.ResponseWriter, r *http.Request) {
-	authID := r.URL.Query().Get("author_id")
-	sortOrder := r.URL.Query().Get("sort")
-	if authID == "" {
-		log.Printf("getting all chirps")
-		chirps, err := cfg.dbQueries.GetChirps(r.Context())
+	query := r.URL.Query()
+	sortOrder := query.Get("sort")
+	if sortOrder == "" {
+		sortOrder = "asc"
+	}
+	if sortOrder != "asc" && sortOrder != "desc" {
+		respondWithError(w, http.StatusBadRequest, []byte("sort must be asc or desc"))
+		return
+	}
+	page, err := parsePageRequest(r)
+	if err != nil {
+		log.Printf("failed to parse page request: %s", err)
+		respondWithError(w, http.StatusBadRequest, []byte("invalid limit or cursor"))
+		return
+	}
+	limit := page.Limit
+	// Fetch one extra row so we know whether there is a next page.
+	pageSize := int32(limit + 1)
+	// Chirps by authors the viewer muted or shares a block with are left
+	// out.
+	viewerID := cfg.viewerID(r)
+	var responses []chirpResponse
+	hasMore := false
+	if authID := query.Get("author_id"); authID != "" {
+		authorUUID, err := uuid.Parse(authID)
 		if err != nil {
-			log.Printf("failed to get all chirps: %s", err)
-			w.WriteHeader(http.StatusInternalServerError)
+			log.Printf("failed to parse author_id: %s", err)
+			respondWithError(w, http.StatusBadRequest, []byte("invalid author_id"))
 			return
 		}
-		type resParameters struct {
-			Id         uuid.UUID `json:"id"`
-			Body       string    `json:"body"`
-			Created_at time.Time `json:"created_at"`
-			Updated_at time.Time `json:"updated_at"`
-			User_id    uuid.UUID `json:"user_id"`
-		}
-		var resParams []resParameters
-		for _, chirp := range chirps {
-			resParams = append(resParams, resParameters{
-				Id:         chirp.ID,
-				Body:       chirp.Body,
-				Created_at: chirp.CreatedAt,
-				Updated_at: chirp.UpdatedAt,
-				User_id:    chirp.UserID,
-			})
+		// An author's listing includes their rechirps.
+		entryParams := database.ListAuthorEntriesAscParams{
+			AuthorID:        authorUUID,
+			ViewerID:        viewerID,
+			CursorCreatedAt: page.CursorCreatedAt,
+			CursorID:        page.CursorID,
+			PageSize:        pageSize,
 		}
+		var entries []feedEntry
 		if sortOrder == "desc" {
-			sort.Slice(resParams, func(i, j int) bool { return resParams[i].Created_at.After(resParams[j].Created_at) })
+			rows, err := cfg.dbQueries.ListAuthorEntriesDesc(r.Context(), database.ListAuthorEntriesDescParams(entryParams))
+			if err != nil {
+				log.Printf("failed to list author entries: %s", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			for _, row := range rows {
+				entries = append(entries, feedEntry(row))
+			}
+		} else {
+			rows, err := cfg.dbQueries.ListAuthorEntriesAsc(r.Context(), entryParams)
+			if err != nil {
+				log.Printf("failed to list author entries: %s", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			for _, row := range rows {
+				entries = append(entries, feedEntry(row))
+			}
 		}
-		dat, err := json.Marshal(resParams)
+		entries, hasMore = trimPage(entries, limit)
+		responses, err = cfg.feedResponses(r.Context(), entries, viewerID)
 		if err != nil {
-			log.Printf("failed to marshal response body: %s", err)
+			log.Printf("failed to build chirp responses: %s", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
-		respondWithJSON(w, http.StatusOK, dat)
 	} else {
-		log.Printf("getting all chirps for userID: %s\n", authID)
-		chirps, err := cfg.dbQueries.GetChirpsByID(r.Context(), uuid.MustParse(authID))
+		listParams := database.ListChirpsAscParams{
+			ViewerID:        viewerID,
+			CursorCreatedAt: page.CursorCreatedAt,
+			CursorID:        page.CursorID,
+			PageSize:        pageSize,
+		}
+		var chirps []database.Chirp
+		if sortOrder == "desc" {
+			chirps, err = cfg.dbQueries.ListChirpsDesc(r.Context(), database.ListChirpsDescParams(listParams))
+		} else {
+			chirps, err = cfg.dbQueries.ListChirpsAsc(r.Context(), listParams)
+		}
 		if err != nil {
-			log.Printf("failed to get all chirps: %s", err)
+			log.Printf("failed to list chirps: %s", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
-		type resParameters struct {
-			Id         uuid.UUID `json:"id"`
-			Body       string    `json:"body"`
-			Created_at time.Time `json:"created_at"`
-			Updated_at time.Time `json:"updated_at"`
-			User_id    uuid.UUID `json:"user_id"`
-		}
-		var resParams []resParameters
-		for _, chirp := range chirps {
-			resParams = append(resParams, resParameters{
-				Id:         chirp.ID,
-				Body:       chirp.Body,
-				Created_at: chirp.CreatedAt,
-				Updated_at: chirp.UpdatedAt,
-				User_id:    chirp.UserID,
-			})
-		}
-		dat, err := json.Marshal(resParams)
+		chirps, hasMore = trimPage(chirps, limit)
+		responses, err = cfg.chirpResponses(r.Context(), chirps, viewerID)
 		if err != nil {
-			log.Printf("failed to marshal response body: %s", err)
+			log.Printf("failed to build chirp responses: %s", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
-		respondWithJSON(w, http.StatusOK, dat)
 	}
+	respondWithChirpPage(w, r, responses, hasMore, limit)
 }
 
 func (cfg *apiConfig) getChirpByID(w http.ResponseWriter, r *http.Request) {
@@ -299,27 +616,19 @@ func (cfg *apiConfig) getChirpByID(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusBadRequest, nil)
 		return
 	}
-	chirp, err := cfg.dbQueries.GetChirpByID(r.Context(), chirpUUID)
+	chirp, err := cfg.dbQueries.GetVisibleChirpByID(r.Context(), chirpUUID)
 	if err != nil {
 		log.Printf("failed to get chirp by id: %s", err)
 		respondWithError(w, http.StatusNotFound, nil)
 		return
 	}
-	type resParameters struct {
-		Id         uuid.UUID `json:"id"`
-		Body       string    `json:"body"`
-		Created_at time.Time `json:"created_at"`
-		Updated_at time.Time `json:"updated_at"`
-		User_id    uuid.UUID `json:"user_id"`
-	}
-	resParam := resParameters{
-		Id:         chirp.ID,
-		Body:       chirp.Body,
-		Created_at: chirp.CreatedAt,
-		Updated_at: chirp.UpdatedAt,
-		User_id:    chirp.UserID,
+	resParams, err := cfg.chirpResponses(r.Context(), []database.Chirp{chirp}, cfg.viewerID(r))
+	if err != nil {
+		log.Printf("failed to build chirp response: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
 	}
-	dat, err := json.Marshal(resParam)
+	dat, err := json.Marshal(resParams[0])
 	if err != nil {
 		log.Printf("failed to marshal response body: %s", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -341,19 +650,45 @@ func (cfg *apiConfig) loginUser(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	if !cfg.checkLoginThrottle(w, r, reqParams.Email) {
+		return
+	}
 	user, err := cfg.dbQueries.GetUserByEmail(r.Context(), reqParams.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		auth.CheckPasswordHash(dummyPasswordHash(), reqParams.Password)
+		cfg.failLogin(w, r, reqParams.Email, uuid.NullUUID{})
+		return
+	}
 	if err != nil {
 		log.Printf("failed to get user by email: %s\n", err)
-		respondWithError(w, http.StatusNotFound, []byte("user not found"))
+		respondWithError(w, http.StatusInternalServerError, nil)
 		return
 	}
 	err = auth.CheckPasswordHash(user.HashedPassword, reqParams.Password)
 	if err != nil {
-		log.Printf("failed to check password hash: %s", err)
-		respondWithError(w, http.StatusUnauthorized, []byte("Incorrect email or password"))
+		cfg.failLogin(w, r, reqParams.Email, uuid.NullUUID{UUID: user.ID, Valid: true})
 		return
 	}
-	token, err := auth.MakeJWT(user.ID, cfg.jwtSecret, time.Duration(3600)*time.Second)
+	_, err = cfg.checkAccount(r.Context(), user.ID)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		cfg.respondWithLoginChallenge(w, r, user)
+		return
+	}
+	cfg.respondWithLogin(w, r, user)
+}
+
+// respondWithLogin completes a login: it clears the account's failed
+// logins and responds with new access and refresh tokens.
+func (cfg *apiConfig) respondWithLogin(w http.ResponseWriter, r *http.Request, user database.User) {
+	err := cfg.dbQueries.ClearLoginFailures(r.Context(), accountLoginSubject(user.Email))
+	if err != nil {
+		log.Printf("failed to clear login failures: %s", err)
+	}
+	token, err := cfg.jwtKeys.MakeJWT(user.ID, user.Role, time.Duration(3600)*time.Second)
 	if err != nil {
 		log.Printf("failed to make JWT: %s", err)
 		respondWithError(w, http.StatusInternalServerError, []byte("failed to make JWT"))
@@ -370,11 +705,16 @@ func (cfg *apiConfig) loginUser(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, []byte("failed to make refresh token"))
 		return
 	}
+	// Only the digest is stored; this response is the one place the
+	// token itself appears.
 	rTokenParams := database.CreateRefreshTokenParams{
-		UserID: user.ID,
-		Token:  refreshToken,
+		UserID:    user.ID,
+		TokenHash: auth.HashToken(refreshToken),
+		FamilyID:  uuid.New(),
+		UserAgent: requestUserAgent(r),
+		Ip:        cfg.clientIP(r),
 	}
-	newRefreshToken, err := cfg.dbQueries.CreateRefreshToken(r.Context(), rTokenParams)
+	_, err = cfg.dbQueries.CreateRefreshToken(r.Context(), rTokenParams)
 	if err != nil {
 		log.Printf("failed to create refresh token: %s", err)
 		respondWithError(w, http.StatusInternalServerError, []byte("failed to create refresh token"))
@@ -388,6 +728,10 @@ func (cfg *apiConfig) loginUser(w http.ResponseWriter, r *http.Request) {
 		Token        string    `json:"token,omitempty"`
 		RefreshToken string    `json:"refresh_token,omitempty"`
 		IsChirpyRed  bool      `json:"is_chirpy_red"`
+		Handle       string    `json:"handle,omitempty"`
+		Role         string    `json:"role"`
+		MustReset    bool      `json:"password_reset_required,omitempty"`
+		Verified     bool      `json:"email_verified"`
 	}
 	resParams := resParameters{
 		Id:           user.ID,
@@ -395,8 +739,12 @@ func (cfg *apiConfig) loginUser(w http.ResponseWriter, r *http.Request) {
 		Updated_at:   user.UpdatedAt,
 		Email:        user.Email,
 		Token:        token,
-		RefreshToken: newRefreshToken.Token,
+		RefreshToken: refreshToken,
 		IsChirpyRed:  user.ChirpyRed.Bool,
+		Handle:       user.Handle.String,
+		Role:         user.Role,
+		MustReset:    user.PasswordResetRequired,
+		Verified:     user.EmailVerifiedAt.Valid,
 	}
 	dat, err := json.Marshal(resParams)
 	if err != nil {
@@ -407,6 +755,10 @@ func (cfg *apiConfig) loginUser(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusOK, dat)
 }
 
+// RefreshToken exchanges a refresh token for a new access token and a new
+// refresh token. The presented token is revoked, so each refresh token
+// works once. Presenting a token that was already exchanged means it was
+// copied, so the whole family descending from its login is revoked.
 func (cfg *apiConfig) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	token, err := auth.GetBearerToken(r.Header)
 	if err != nil {
@@ -414,12 +766,37 @@ func (cfg *apiConfig) RefreshToken(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusUnauthorized, []byte("failed to get refresh token"))
 		return
 	}
-	refreshToken, err := cfg.dbQueries.GetRefreshToken(r.Context(), token)
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("failed to begin transaction: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+	refreshToken, err := qtx.GetRefreshTokenForUpdate(r.Context(), auth.HashToken(token))
 	if err != nil {
 		log.Printf("failed to get refresh token: %s", err)
 		respondWithError(w, http.StatusUnauthorized, []byte("failed to get refresh token"))
 		return
 	}
+	if refreshToken.RotatedAt.Valid {
+		log.Printf("refresh token reused, revoking family %s", refreshToken.FamilyID)
+		err = qtx.RevokeRefreshTokenFamily(r.Context(), refreshToken.FamilyID)
+		if err != nil {
+			log.Printf("failed to revoke refresh token family: %s", err)
+			respondWithError(w, http.StatusInternalServerError, nil)
+			return
+		}
+		err = tx.Commit()
+		if err != nil {
+			log.Printf("failed to commit refresh token family revocation: %s", err)
+			respondWithError(w, http.StatusInternalServerError, nil)
+			return
+		}
+		respondWithError(w, http.StatusUnauthorized, []byte("refresh token reused"))
+		return
+	}
 	if refreshToken.ExpiresAt.Before(time.Now()) {
 		log.Printf("refresh token expired")
 		respondWithError(w, http.StatusUnauthorized, []byte("refresh token expired"))
@@ -430,13 +807,18 @@ func (cfg *apiConfig) RefreshToken(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusUnauthorized, []byte("refresh token revoked"))
 		return
 	}
-	user, err := cfg.dbQueries.GetUserByRefreshToken(r.Context(), refreshToken.Token)
+	user, err := qtx.GetUserByID(r.Context(), refreshToken.UserID)
 	if err != nil {
 		log.Printf("failed to get user by refresh token: %s", err)
 		respondWithError(w, http.StatusUnauthorized, []byte("failed to get user by refresh token"))
 		return
 	}
-	newToken, err := auth.MakeJWT(user.ID, cfg.jwtSecret, time.Duration(3600)*time.Second)
+	_, err = cfg.checkAccount(r.Context(), user.ID)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	newToken, err := cfg.jwtKeys.MakeJWT(user.ID, user.Role, time.Duration(3600)*time.Second)
 	if err != nil {
 		log.Printf("failed to make JWT: %s", err)
 		respondWithError(w, http.StatusInternalServerError, []byte("failed to make JWT"))
@@ -448,21 +830,38 @@ func (cfg *apiConfig) RefreshToken(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, []byte("failed to make refresh token"))
 		return
 	}
+	err = qtx.RotateRefreshToken(r.Context(), refreshToken.TokenHash)
+	if err != nil {
+		log.Printf("failed to rotate refresh token: %s", err)
+		respondWithError(w, http.StatusInternalServerError, []byte("failed to rotate refresh token"))
+		return
+	}
 	rTokenParams := database.CreateRefreshTokenParams{
-		UserID: user.ID,
-		Token:  newRefreshToken,
+		UserID:    user.ID,
+		TokenHash: auth.HashToken(newRefreshToken),
+		FamilyID:  refreshToken.FamilyID,
+		UserAgent: requestUserAgent(r),
+		Ip:        cfg.clientIP(r),
 	}
-	_, err = cfg.dbQueries.CreateRefreshToken(r.Context(), rTokenParams)
+	_, err = qtx.CreateRefreshToken(r.Context(), rTokenParams)
 	if err != nil {
 		log.Printf("failed to create refresh token: %s", err)
 		respondWithError(w, http.StatusInternalServerError, []byte("failed to create refresh token"))
 		return
 	}
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("failed to commit refresh token rotation: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
 	type resParameters struct {
-		Token string `json:"token"`
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
 	}
 	resParams := resParameters{
-		Token: newToken,
+		Token:        newToken,
+		RefreshToken: newRefreshToken,
 	}
 	dat, err := json.Marshal(resParams)
 	if err != nil {
@@ -480,7 +879,7 @@ func (cfg *apiConfig) RevokeToken(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusUnauthorized, nil)
 		return
 	}
-	err = cfg.dbQueries.RevokeRefreshToken(r.Context(), token)
+	err = cfg.dbQueries.RevokeRefreshToken(r.Context(), auth.HashToken(token))
 	if err != nil {
 		log.Printf("failed to revoke refresh token: %s", err)
 		respondWithError(w, http.StatusInternalServerError, []byte("failed to revoke refresh token"))
@@ -489,17 +888,20 @@ func (cfg *apiConfig) RevokeToken(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusNoContent, nil)
 }
 
+// updateUser changes the caller's email, password and handle. It stays
+// available to accounts that must reset their password, and setting the
+// password lifts that requirement.
 func (cfg *apiConfig) updateUser(w http.ResponseWriter, r *http.Request) {
-	token, err := auth.GetBearerToken(r.Header)
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	claims, err := cfg.authenticateClaims(r, true)
 	if err != nil {
-		log.Printf("failed to validate JWTToken: %s", err)
-		respondWithError(w, http.StatusUnauthorized, nil)
+		respondWithAuthError(w, err)
 		return
 	}
+	userID := claims.UserID()
 	type reqParameters struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Email    string  `json:"email"`
+		Password string  `json:"password"`
+		Handle   *string `json:"handle"`
 	}
 	decoder := json.NewDecoder(r.Body)
 	reqParams := reqParameters{}
@@ -509,36 +911,166 @@ func (cfg *apiConfig) updateUser(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	hashedPassword, err := auth.HashPassword(reqParams.Password)
+	currentUser, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
 	if err != nil {
-		log.Printf("failed to hash password: %s", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		log.Printf("failed to get user by id: %s", err)
+		respondWithError(w, http.StatusInternalServerError, []byte("failed to update user"))
 		return
 	}
+	// An omitted password keeps the current one. A forced reset is only
+	// cleared by a password that differs from the current one.
+	passwordChanged := reqParams.Password != "" && auth.CheckPasswordHash(currentUser.HashedPassword, reqParams.Password) != nil
+	if currentUser.PasswordResetRequired && !passwordChanged {
+		respondWithError(w, http.StatusBadRequest, []byte("a new password is required"))
+		return
+	}
+	hashedPassword := currentUser.HashedPassword
+	if passwordChanged {
+		hashedPassword, err = auth.HashPassword(reqParams.Password)
+		if err != nil {
+			log.Printf("failed to hash password: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+	// A new email only replaces the current one once it is verified.
+	pendingEmail := ""
+	if reqParams.Email != "" && reqParams.Email != currentUser.Email {
+		if !mail.ValidAddress(reqParams.Email) {
+			respondWithError(w, http.StatusBadRequest, []byte("invalid email"))
+			return
+		}
+		_, err = cfg.dbQueries.GetUserByEmail(r.Context(), reqParams.Email)
+		if err == nil {
+			respondWithError(w, http.StatusConflict, []byte("email already taken"))
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("failed to get user by email: %s", err)
+			respondWithError(w, http.StatusInternalServerError, []byte("failed to update user"))
+			return
+		}
+		pendingEmail = reqParams.Email
+	}
+	// The handle is only touched when the request includes it; an empty
+	// string clears it.
+	handle := sql.NullString{}
+	if reqParams.Handle != nil && *reqParams.Handle != "" {
+		handle.String = chirptext.NormalizeHandle(*reqParams.Handle)
+		handle.Valid = true
+		if !chirptext.ValidHandle(handle.String) {
+			respondWithError(w, http.StatusBadRequest, []byte("invalid handle"))
+			return
+		}
+		owners, err := cfg.dbQueries.GetUsersByHandles(r.Context(), []string{handle.String})
+		if err != nil {
+			log.Printf("failed to get users by handle: %s", err)
+			respondWithError(w, http.StatusInternalServerError, []byte("failed to update user"))
+			return
+		}
+		for _, owner := range owners {
+			if owner.ID != userID {
+				respondWithError(w, http.StatusConflict, []byte("handle already taken"))
+				return
+			}
+		}
+	}
+
+	// Everything is validated; apply the changes together so a failure
+	// leaves no half-made update or verification token behind.
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("failed to begin transaction: %s", err)
+		respondWithError(w, http.StatusInternalServerError, []byte("failed to update user"))
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+	if reqParams.Handle != nil {
+		err = qtx.UpdateUserHandle(r.Context(), database.UpdateUserHandleParams{
+			Handle: handle,
+			ID:     userID,
+		})
+		if isUniqueViolation(err) {
+			respondWithError(w, http.StatusConflict, []byte("handle already taken"))
+			return
+		}
+		if err != nil {
+			log.Printf("failed to update user handle: %s", err)
+			respondWithError(w, http.StatusInternalServerError, []byte("failed to update user"))
+			return
+		}
+	}
 	updateParams := database.UpdateUserParams{
-		ID:             userID,
-		Email:          reqParams.Email,
-		HashedPassword: hashedPassword,
+		ID:              userID,
+		Email:           currentUser.Email,
+		HashedPassword:  hashedPassword,
+		PasswordChanged: passwordChanged,
 	}
-	updatedUser, err := cfg.dbQueries.UpdateUser(r.Context(), updateParams)
+	updatedUser, err := qtx.UpdateUser(r.Context(), updateParams)
 	if err != nil {
 		log.Printf("failed to update user: %s", err)
 		respondWithError(w, http.StatusInternalServerError, []byte("failed to update user"))
 		return
 	}
+	var verification mail.Message
+	if pendingEmail != "" {
+		verification, err = createEmailVerification(r.Context(), qtx, userID, pendingEmail)
+		if errors.Is(err, errTooManyVerificationEmails) {
+			respondWithError(w, http.StatusTooManyRequests, []byte("too many verification emails, try again later"))
+			return
+		}
+		if err != nil {
+			log.Printf("failed to create email verification: %s", err)
+			respondWithError(w, http.StatusInternalServerError, []byte("failed to update user"))
+			return
+		}
+	}
+	// A new password signs the account out everywhere, in case the old
+	// one leaked.
+	if passwordChanged {
+		err = qtx.RevokeUserRefreshTokens(r.Context(), userID)
+		if err != nil {
+			log.Printf("failed to revoke refresh tokens: %s", err)
+			respondWithError(w, http.StatusInternalServerError, []byte("failed to update user"))
+			return
+		}
+		err = qtx.ExpirePasswordResetTokens(r.Context(), userID)
+		if err != nil {
+			log.Printf("failed to expire password reset tokens: %s", err)
+			respondWithError(w, http.StatusInternalServerError, []byte("failed to update user"))
+			return
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("failed to commit user update: %s", err)
+		respondWithError(w, http.StatusInternalServerError, []byte("failed to update user"))
+		return
+	}
+	cfg.accountCache.Delete(updatedUser.ID)
+	if pendingEmail != "" {
+		cfg.sendMail(verification)
+	}
 	type resParameters struct {
-		Id          uuid.UUID `json:"id"`
-		Created_at  time.Time `json:"created_at"`
-		Updated_at  time.Time `json:"updated_at"`
-		Email       string    `json:"email"`
-		IsChirpyRed bool      `json:"is_chirpy_red"`
+		Id            uuid.UUID `json:"id"`
+		Created_at    time.Time `json:"created_at"`
+		Updated_at    time.Time `json:"updated_at"`
+		Email         string    `json:"email"`
+		IsChirpyRed   bool      `json:"is_chirpy_red"`
+		Handle        string    `json:"handle,omitempty"`
+		EmailVerified bool      `json:"email_verified"`
+		PendingEmail  string    `json:"pending_email,omitempty"`
 	}
 	resParams := resParameters{
-		Id:          updatedUser.ID,
-		Created_at:  updatedUser.CreatedAt,
-		Updated_at:  updatedUser.UpdatedAt,
-		Email:       updatedUser.Email,
-		IsChirpyRed: updatedUser.ChirpyRed.Bool,
+		Id:            updatedUser.ID,
+		Created_at:    updatedUser.CreatedAt,
+		Updated_at:    updatedUser.UpdatedAt,
+		Email:         updatedUser.Email,
+		IsChirpyRed:   updatedUser.ChirpyRed.Bool,
+		Handle:        updatedUser.Handle.String,
+		EmailVerified: currentUser.EmailVerifiedAt.Valid,
+		PendingEmail:  pendingEmail,
 	}
 	dat, err := json.Marshal(resParams)
 	if err != nil {
@@ -550,16 +1082,13 @@ func (cfg *apiConfig) updateUser(w http.ResponseWriter, r *http.Request) {
 }
 
 func (cfg *apiConfig) deleteChirpByID(w http.ResponseWriter, r *http.Request) {
-	token, err := auth.GetBearerToken(r.Header)
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	userID, err := cfg.authenticate(r)
 	if err != nil {
-		log.Printf("failed to validate JWTToken: %s", err)
-		respondWithError(w, http.StatusUnauthorized, nil)
+		respondWithAuthError(w, err)
 		return
 	}
-	id := r.PathValue("chirpID")
-	chirpUUID := uuid.MustParse(id)
-	if id == "" {
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
 		respondWithError(w, http.StatusBadRequest, nil)
 		return
 	}
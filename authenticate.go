@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/UUest/gohttp/internal/auth"
+	"github.com/UUest/gohttp/internal/database"
+)
+
+// accountStatusTTL bounds how long a suspension or role change made on
+// another instance can take to apply here. Changes made through this
+// instance apply at once.
+const accountStatusTTL = 30 * time.Second
+
+var errPasswordResetRequired = errors.New("password reset required")
+
+// suspensionError is returned for requests from suspended or banned
+// accounts.
+type suspensionError struct {
+	Reason string
+	Until  sql.NullTime
+}
+
+func (e *suspensionError) Error() string {
+	msg := "account banned"
+	if e.Until.Valid {
+		msg = "account suspended until " + e.Until.Time.Format(time.RFC3339)
+	}
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+type claimsContextKey struct{}
+
+// accountStatus returns the suspension state, role and password reset flag
+// of a user, served from a short-lived cache.
+func (cfg *apiConfig) accountStatus(ctx context.Context, userID uuid.UUID) (database.GetUserAccountStatusRow, error) {
+	if status, ok := cfg.accountCache.Get(userID); ok {
+		return status, nil
+	}
+	status, err := cfg.dbQueries.GetUserAccountStatus(ctx, userID)
+	if err != nil {
+		return database.GetUserAccountStatusRow{}, err
+	}
+	cfg.accountCache.Set(userID, status)
+	return status, nil
+}
+
+// checkAccount returns a suspensionError when the account may not act.
+func (cfg *apiConfig) checkAccount(ctx context.Context, userID uuid.UUID) (database.GetUserAccountStatusRow, error) {
+	status, err := cfg.accountStatus(ctx, userID)
+	if err != nil {
+		return database.GetUserAccountStatusRow{}, err
+	}
+	if status.Suspended {
+		return status, &suspensionError{Reason: status.SuspensionReason, Until: status.SuspendedUntil}
+	}
+	return status, nil
+}
+
+// authenticate validates the request's access token and checks that the
+// account behind it may still act. It returns the caller's user ID.
+func (cfg *apiConfig) authenticate(r *http.Request) (uuid.UUID, error) {
+	claims, err := cfg.authenticateClaims(r, false)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return claims.UserID(), nil
+}
+
+// authenticateClaims is authenticate returning the full claims. The role
+// is taken from the account status, so role changes apply without waiting
+// for new tokens. Accounts that must reset their password are only let
+// through when allowPasswordReset is set, which the password change
+// endpoint does.
+func (cfg *apiConfig) authenticateClaims(r *http.Request, allowPasswordReset bool) (*auth.Claims, error) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := cfg.jwtKeys.ParseJWT(token)
+	if err != nil {
+		return nil, err
+	}
+	status, err := cfg.checkAccount(r.Context(), claims.UserID())
+	if err != nil {
+		return nil, err
+	}
+	if status.PasswordResetRequired && !allowPasswordReset {
+		return nil, errPasswordResetRequired
+	}
+	claims.Role = status.Role
+	return claims, nil
+}
+
+// respondWithAuthError writes the response for an error returned by
+// authenticate.
+func respondWithAuthError(w http.ResponseWriter, err error) {
+	var suspended *suspensionError
+	switch {
+	case errors.As(err, &suspended):
+		respondWithError(w, http.StatusForbidden, []byte(suspended.Error()))
+	case errors.Is(err, errPasswordResetRequired):
+		respondWithError(w, http.StatusForbidden, []byte("password reset required"))
+	case errors.Is(err, errEmailNotVerified):
+		respondWithError(w, http.StatusForbidden, []byte("verify your email address first"))
+	case errors.Is(err, sql.ErrNoRows):
+		respondWithError(w, http.StatusUnauthorized, nil)
+	case errors.Is(err, auth.ErrTokenExpired):
+		// Expiry is routine, so clients are told to refresh rather than
+		// the error being logged.
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token", error_description="token expired"`)
+		respondWithError(w, http.StatusUnauthorized, []byte("token expired"))
+	case errors.Is(err, auth.ErrTokenMalformed):
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token", error_description="malformed token"`)
+		respondWithError(w, http.StatusUnauthorized, []byte("malformed token"))
+	default:
+		log.Printf("failed to authenticate request: %s", err)
+		respondWithError(w, http.StatusUnauthorized, nil)
+	}
+}
+
+// claimsFromContext returns the claims requireRole stored for the request.
+func claimsFromContext(ctx context.Context) (*auth.Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey{}).(*auth.Claims)
+	return claims, ok
+}
+
+// parseSuspensionDuration parses the optional duration of a suspension.
+// An empty string means a permanent ban.
+func parseSuspensionDuration(s string) (sql.NullInt32, error) {
+	if s == "" {
+		return sql.NullInt32{}, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return sql.NullInt32{}, fmt.Errorf("invalid duration: %w", err)
+	}
+	if d < time.Second || d.Seconds() > float64(1<<31-1) {
+		return sql.NullInt32{}, fmt.Errorf("duration out of range")
+	}
+	return sql.NullInt32{Int32: int32(d.Seconds()), Valid: true}, nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/UUest/gohttp/internal/database"
+)
+
+// relationTarget authenticates the caller and resolves the {userID} path
+// value for block and mute requests. It writes the error response and
+// returns false when the request cannot go ahead.
+func (cfg *apiConfig) relationTarget(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return uuid.Nil, uuid.Nil, false
+	}
+	targetID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, nil)
+		return uuid.Nil, uuid.Nil, false
+	}
+	if targetID == userID {
+		respondWithError(w, http.StatusBadRequest, []byte("cannot target yourself"))
+		return uuid.Nil, uuid.Nil, false
+	}
+	_, err = cfg.dbQueries.GetUserByID(r.Context(), targetID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, nil)
+		return uuid.Nil, uuid.Nil, false
+	}
+	if err != nil {
+		log.Printf("failed to get user by id: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, targetID, true
+}
+
+// blockUser blocks a user and removes any follows between the two of them.
+// Neither side can follow, reply to or mention the other while the block
+// stands, and neither sees the other's chirps.
+func (cfg *apiConfig) blockUser(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationTarget(w, r)
+	if !ok {
+		return
+	}
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("failed to begin transaction: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+	err = qtx.BlockUser(r.Context(), database.BlockUserParams{
+		BlockerID: userID,
+		BlockedID: targetID,
+	})
+	if err != nil {
+		log.Printf("failed to block user: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	err = qtx.DeleteFollowsBetween(r.Context(), database.DeleteFollowsBetweenParams{
+		UserA: userID,
+		UserB: targetID,
+	})
+	if err != nil {
+		log.Printf("failed to delete follows: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("failed to commit block: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (cfg *apiConfig) unblockUser(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationTarget(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.dbQueries.UnblockUser(r.Context(), database.UnblockUserParams{
+		BlockerID: userID,
+		BlockedID: targetID,
+	})
+	if err != nil {
+		log.Printf("failed to unblock user: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// muteUser hides a user's chirps from the caller without them knowing.
+func (cfg *apiConfig) muteUser(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationTarget(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.dbQueries.MuteUser(r.Context(), database.MuteUserParams{
+		MuterID: userID,
+		MutedID: targetID,
+	})
+	if err != nil {
+		log.Printf("failed to mute user: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (cfg *apiConfig) unmuteUser(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationTarget(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.dbQueries.UnmuteUser(r.Context(), database.UnmuteUserParams{
+		MuterID: userID,
+		MutedID: targetID,
+	})
+	if err != nil {
+		log.Printf("failed to unmute user: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
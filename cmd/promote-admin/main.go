@@ -0,0 +1,60 @@
+// Command promote-admin gives the admin role to an existing user. It is
+// meant for bootstrapping a new deployment, so it refuses to run once an
+// admin exists unless -force is set; after that, admins manage roles
+// through PUT /admin/users/{userID}/role.
+//
+//	go run ./cmd/promote-admin -email admin@example.com
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+
+	"github.com/UUest/gohttp/internal/auth"
+	"github.com/UUest/gohttp/internal/database"
+)
+
+func main() {
+	email := flag.String("email", "", "email of the user to promote")
+	force := flag.Bool("force", false, "promote even if an admin already exists")
+	flag.Parse()
+	if *email == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	godotenv.Load()
+	db, err := sql.Open("postgres", os.Getenv("DB_URL"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+	dbQueries := database.New(db)
+	ctx := context.Background()
+
+	admins, err := dbQueries.CountUsersWithRole(ctx, auth.RoleAdmin)
+	if err != nil {
+		log.Fatalf("failed to count admins: %s", err)
+	}
+	if admins > 0 && !*force {
+		log.Fatalf("%d admin(s) already exist; use -force to promote another", admins)
+	}
+	user, err := dbQueries.PromoteUserByEmail(ctx, database.PromoteUserByEmailParams{
+		Role:  auth.RoleAdmin,
+		Email: *email,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Fatalf("no user with email %s", *email)
+	}
+	if err != nil {
+		log.Fatalf("failed to promote user: %s", err)
+	}
+	log.Printf("promoted %s (%s) to admin", user.Email, user.ID)
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/UUest/gohttp/internal/auth"
+	"github.com/UUest/gohttp/internal/database"
+	"github.com/UUest/gohttp/internal/mail"
+)
+
+const (
+	emailVerificationTTL = 24 * time.Hour
+	// maxEmailVerificationsPerHour caps the verification emails sent for
+	// one account, so it can't be used to flood other addresses.
+	maxEmailVerificationsPerHour = 3
+)
+
+var (
+	errEmailNotVerified          = errors.New("email not verified")
+	errTooManyVerificationEmails = errors.New("too many verification emails")
+)
+
+// startEmailVerification mails a token that proves ownership of email.
+func (cfg *apiConfig) startEmailVerification(ctx context.Context, userID uuid.UUID, email string) error {
+	msg, err := createEmailVerification(ctx, cfg.dbQueries, userID, email)
+	if err != nil {
+		return err
+	}
+	cfg.sendMail(msg)
+	return nil
+}
+
+// createEmailVerification stores a token that proves ownership of email
+// and returns the message carrying it. Outstanding tokens for the user are
+// expired first, so only the latest address asked for can be confirmed.
+// Callers inside a transaction send the message once it commits.
+func createEmailVerification(ctx context.Context, q *database.Queries, userID uuid.UUID, email string) (mail.Message, error) {
+	recent, err := q.CountRecentEmailVerificationTokens(ctx, database.CountRecentEmailVerificationTokensParams{
+		UserID:        userID,
+		WindowSeconds: int32(time.Hour.Seconds()),
+	})
+	if err != nil {
+		return mail.Message{}, err
+	}
+	if recent >= maxEmailVerificationsPerHour {
+		return mail.Message{}, errTooManyVerificationEmails
+	}
+	token, err := auth.MakeToken()
+	if err != nil {
+		return mail.Message{}, err
+	}
+	err = q.ExpireEmailVerificationTokens(ctx, userID)
+	if err != nil {
+		return mail.Message{}, err
+	}
+	err = q.CreateEmailVerificationToken(ctx, database.CreateEmailVerificationTokenParams{
+		UserID:     userID,
+		Email:      email,
+		TokenHash:  auth.HashToken(token),
+		TtlSeconds: int32(emailVerificationTTL.Seconds()),
+	})
+	if err != nil {
+		return mail.Message{}, err
+	}
+	return mail.Message{
+		To:      email,
+		Subject: "Verify your Chirpy email address",
+		Body: fmt.Sprintf("To confirm this address for your Chirpy account, send this token to POST /api/email/verify within %d hours:\n\n%s\n\n"+
+			"If you didn't ask for this, ignore this email.\n",
+			int(emailVerificationTTL.Hours()), token),
+	}, nil
+}
+
+// checkEmailVerified returns errEmailNotVerified when verification is
+// enforced and the user hasn't verified their address.
+func (cfg *apiConfig) checkEmailVerified(ctx context.Context, userID uuid.UUID) error {
+	if !cfg.requireEmailVerification {
+		return nil
+	}
+	status, err := cfg.accountStatus(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !status.EmailVerified {
+		return errEmailNotVerified
+	}
+	return nil
+}
+
+// verifyEmail confirms the address a verification token was sent to. For
+// pending email changes this is when the account's email changes.
+func (cfg *apiConfig) verifyEmail(w http.ResponseWriter, r *http.Request) {
+	type reqParameters struct {
+		Token string `json:"token"`
+	}
+	reqParams := reqParameters{}
+	err := json.NewDecoder(r.Body).Decode(&reqParams)
+	if err != nil || reqParams.Token == "" {
+		respondWithError(w, http.StatusBadRequest, []byte("token is required"))
+		return
+	}
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("failed to begin transaction: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+	verification, err := qtx.UseEmailVerificationToken(r.Context(), auth.HashToken(reqParams.Token))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusBadRequest, []byte("invalid or expired token"))
+		return
+	}
+	if err != nil {
+		log.Printf("failed to use email verification token: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	user, err := qtx.ConfirmUserEmail(r.Context(), database.ConfirmUserEmailParams{
+		Email: verification.Email,
+		ID:    verification.UserID,
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, []byte("email already taken"))
+		return
+	}
+	if err != nil {
+		log.Printf("failed to confirm user email: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	err = qtx.ExpireEmailVerificationTokens(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("failed to expire email verification tokens: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("failed to commit email verification: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	cfg.accountCache.Delete(user.ID)
+	type resParameters struct {
+		Id             uuid.UUID `json:"id"`
+		Email          string    `json:"email"`
+		Email_verified bool      `json:"email_verified"`
+	}
+	dat, err := json.Marshal(resParameters{
+		Id:             user.ID,
+		Email:          user.Email,
+		Email_verified: true,
+	})
+	if err != nil {
+		log.Printf("failed to marshal response body: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, dat)
+}
+
+// resendEmailVerification mails a new token for the pending email change,
+// or for the current address if it isn't verified yet.
+func (cfg *apiConfig) resendEmailVerification(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	email, err := cfg.dbQueries.GetPendingEmail(r.Context(), userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("failed to get pending email: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+		if err != nil {
+			log.Printf("failed to get user by id: %s", err)
+			respondWithError(w, http.StatusInternalServerError, nil)
+			return
+		}
+		if user.EmailVerifiedAt.Valid {
+			respondWithError(w, http.StatusConflict, []byte("email already verified"))
+			return
+		}
+		email = user.Email
+	}
+	err = cfg.startEmailVerification(r.Context(), userID, email)
+	if errors.Is(err, errTooManyVerificationEmails) {
+		respondWithError(w, http.StatusTooManyRequests, []byte("too many verification emails, try again later"))
+		return
+	}
+	if err != nil {
+		log.Printf("failed to start email verification: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/UUest/gohttp/internal/database"
+)
+
+func (cfg *apiConfig) followUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, nil)
+		return
+	}
+	if followeeID == userID {
+		respondWithError(w, http.StatusBadRequest, []byte("cannot follow yourself"))
+		return
+	}
+	_, err = cfg.dbQueries.GetUserByID(r.Context(), followeeID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, nil)
+		return
+	}
+	if err != nil {
+		log.Printf("failed to get user by id: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	blocked, err := cfg.dbQueries.IsBlockedBetween(r.Context(), database.IsBlockedBetweenParams{
+		UserA: userID,
+		UserB: followeeID,
+	})
+	if err != nil {
+		log.Printf("failed to check blocks: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	if blocked {
+		respondWithError(w, http.StatusForbidden, []byte("cannot follow this user"))
+		return
+	}
+	err = cfg.dbQueries.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		log.Printf("failed to follow user: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (cfg *apiConfig) unfollowUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, nil)
+		return
+	}
+	err = cfg.dbQueries.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		log.Printf("failed to unfollow user: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// followParameters is one entry of a followers or following listing.
+type followParameters struct {
+	User_id     uuid.UUID `json:"user_id"`
+	Followed_at time.Time `json:"followed_at"`
+}
+
+func (cfg *apiConfig) getFollowers(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, nil)
+		return
+	}
+	page, err := parsePageRequest(r)
+	if err != nil {
+		log.Printf("failed to parse page request: %s", err)
+		respondWithError(w, http.StatusBadRequest, []byte("invalid limit or cursor"))
+		return
+	}
+	rows, err := cfg.dbQueries.ListFollowers(r.Context(), database.ListFollowersParams{
+		UserID:          userID,
+		CursorCreatedAt: page.CursorCreatedAt,
+		CursorID:        page.CursorID,
+		PageSize:        int32(page.Limit + 1),
+	})
+	if err != nil {
+		log.Printf("failed to list followers: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	follows := make([]followParameters, 0, len(rows))
+	for _, row := range rows {
+		follows = append(follows, followParameters{
+			User_id:     row.FollowerID,
+			Followed_at: row.CreatedAt,
+		})
+	}
+	respondWithFollows(w, r, follows, page.Limit)
+}
+
+func (cfg *apiConfig) getFollowing(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, nil)
+		return
+	}
+	page, err := parsePageRequest(r)
+	if err != nil {
+		log.Printf("failed to parse page request: %s", err)
+		respondWithError(w, http.StatusBadRequest, []byte("invalid limit or cursor"))
+		return
+	}
+	rows, err := cfg.dbQueries.ListFollowing(r.Context(), database.ListFollowingParams{
+		UserID:          userID,
+		CursorCreatedAt: page.CursorCreatedAt,
+		CursorID:        page.CursorID,
+		PageSize:        int32(page.Limit + 1),
+	})
+	if err != nil {
+		log.Printf("failed to list following: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	follows := make([]followParameters, 0, len(rows))
+	for _, row := range rows {
+		follows = append(follows, followParameters{
+			User_id:     row.FolloweeID,
+			Followed_at: row.CreatedAt,
+		})
+	}
+	respondWithFollows(w, r, follows, page.Limit)
+}
+
+// respondWithFollows trims the extra row fetched past the page limit and
+// writes the page along with its next cursor.
+func respondWithFollows(w http.ResponseWriter, r *http.Request, follows []followParameters, limit int) {
+	nextCursor := ""
+	if len(follows) > limit {
+		follows = follows[:limit]
+		last := follows[len(follows)-1]
+		nextCursor = encodeCursor(pageCursor{CreatedAt: last.Followed_at, ID: last.User_id})
+		setNextLink(w, r, nextCursor, limit)
+	}
+	type resParameters struct {
+		Users      []followParameters `json:"users"`
+		NextCursor string             `json:"next_cursor,omitempty"`
+	}
+	dat, err := json.Marshal(resParameters{
+		Users:      follows,
+		NextCursor: nextCursor,
+	})
+	if err != nil {
+		log.Printf("failed to marshal response body: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, dat)
+}
+
+// getTimeline returns the caller's own chirps and rechirps and those of
+// everyone they follow, newest first.
+func (cfg *apiConfig) getTimeline(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	page, err := parsePageRequest(r)
+	if err != nil {
+		log.Printf("failed to parse page request: %s", err)
+		respondWithError(w, http.StatusBadRequest, []byte("invalid limit or cursor"))
+		return
+	}
+	limit := page.Limit
+	rows, err := cfg.dbQueries.ListTimelineEntries(r.Context(), database.ListTimelineEntriesParams{
+		ViewerID:        userID,
+		CursorCreatedAt: page.CursorCreatedAt,
+		CursorID:        page.CursorID,
+		PageSize:        int32(limit + 1),
+	})
+	if err != nil {
+		log.Printf("failed to list timeline: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	entries := make([]feedEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, feedEntry(row))
+	}
+	entries, hasMore := trimPage(entries, limit)
+	responses, err := cfg.feedResponses(r.Context(), entries, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		log.Printf("failed to build chirp responses: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	respondWithChirpPage(w, r, responses, hasMore, limit)
+}
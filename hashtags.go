@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/UUest/gohttp/internal/chirptext"
+	"github.com/UUest/gohttp/internal/database"
+)
+
+const (
+	defaultTrendingWindow = 24 * time.Hour
+	maxTrendingWindow     = 7 * 24 * time.Hour
+	defaultTrendingLimit  = 10
+	maxTrendingLimit      = 50
+)
+
+// saveChirpHashtags replaces the hashtags stored for chirp with the ones
+// found in its current body.
+func saveChirpHashtags(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	err := q.DeleteChirpHashtags(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+	for _, tag := range chirptext.Hashtags(chirp.Body) {
+		err = q.AddChirpHashtag(ctx, database.AddChirpHashtagParams{
+			ChirpID:   chirp.ID,
+			Tag:       tag,
+			CreatedAt: chirp.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cfg *apiConfig) getHashtagChirps(w http.ResponseWriter, r *http.Request) {
+	tag := chirptext.NormalizeHashtag(r.PathValue("tag"))
+	if tag == "" {
+		respondWithError(w, http.StatusBadRequest, nil)
+		return
+	}
+	page, err := parsePageRequest(r)
+	if err != nil {
+		log.Printf("failed to parse page request: %s", err)
+		respondWithError(w, http.StatusBadRequest, []byte("invalid limit or cursor"))
+		return
+	}
+	chirps, err := cfg.dbQueries.ListHashtagChirps(r.Context(), database.ListHashtagChirpsParams{
+		Tag:             tag,
+		CursorCreatedAt: page.CursorCreatedAt,
+		CursorID:        page.CursorID,
+		PageSize:        int32(page.Limit + 1),
+	})
+	if err != nil {
+		log.Printf("failed to list hashtag chirps: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	chirps, hasMore := trimPage(chirps, page.Limit)
+	responses, err := cfg.chirpResponses(r.Context(), chirps, cfg.viewerID(r))
+	if err != nil {
+		log.Printf("failed to build chirp responses: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	respondWithChirpPage(w, r, responses, hasMore, page.Limit)
+}
+
+// getTrendingHashtags ranks hashtags by how many chirps used them within
+// the trailing window, e.g. ?window=6h. The window defaults to 24 hours.
+func (cfg *apiConfig) getTrendingHashtags(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	window := defaultTrendingWindow
+	if rawWindow := query.Get("window"); rawWindow != "" {
+		parsed, err := time.ParseDuration(rawWindow)
+		if err != nil || parsed <= 0 || parsed > maxTrendingWindow {
+			respondWithError(w, http.StatusBadRequest, []byte("invalid window"))
+			return
+		}
+		window = parsed
+	}
+	limit := defaultTrendingLimit
+	if rawLimit := query.Get("limit"); rawLimit != "" {
+		parsed, err := strconv.Atoi(rawLimit)
+		if err != nil || parsed < 1 {
+			respondWithError(w, http.StatusBadRequest, []byte("invalid limit"))
+			return
+		}
+		limit = min(parsed, maxTrendingLimit)
+	}
+	rows, err := cfg.dbQueries.GetTrendingHashtags(r.Context(), database.GetTrendingHashtagsParams{
+		WindowSeconds: int32(window.Seconds()),
+		MaxTags:       int32(limit),
+	})
+	if err != nil {
+		log.Printf("failed to get trending hashtags: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	type tagParameters struct {
+		Tag         string `json:"tag"`
+		Chirp_count int64  `json:"chirp_count"`
+	}
+	type resParameters struct {
+		Window string          `json:"window"`
+		Tags   []tagParameters `json:"tags"`
+	}
+	resParams := resParameters{
+		Window: window.String(),
+		Tags:   []tagParameters{},
+	}
+	for _, row := range rows {
+		resParams.Tags = append(resParams.Tags, tagParameters{
+			Tag:         row.Tag,
+			Chirp_count: row.ChirpCount,
+		})
+	}
+	dat, err := json.Marshal(resParams)
+	if err != nil {
+		log.Printf("failed to marshal response body: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, dat)
+}
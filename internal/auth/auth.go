@@ -2,6 +2,7 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"net/http"
@@ -25,32 +26,63 @@ func CheckPasswordHash(hash, password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
 
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
-		Subject:   userID.String(),
-	})
-	return jwtToken.SignedString([]byte(tokenSecret))
+// Roles a user can hold. Admins can do everything moderators can.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Claims are the claims carried by access tokens.
+type Claims struct {
+	Role string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// MakeJWT issues an HS256 access token signed with tokenSecret.
+func MakeJWT(userID uuid.UUID, role, tokenSecret string, expiresIn time.Duration) (string, error) {
+	ks, err := NewKeySet(ValidatorConfig{}, NewHMACKey("", []byte(tokenSecret)))
+	if err != nil {
+		return "", err
+	}
+	return ks.MakeJWT(userID, role, expiresIn)
+}
+
+// ParseJWT validates an HS256 access token signed with tokenSecret and
+// returns its claims.
+func ParseJWT(tokenString, tokenSecret string) (*Claims, error) {
+	ks, err := NewKeySet(ValidatorConfig{}, NewHMACKey("", []byte(tokenSecret)))
+	if err != nil {
+		return nil, err
+	}
+	return ks.ParseJWT(tokenString)
+}
+
+// UserID returns the user the token was issued to.
+func (c *Claims) UserID() uuid.UUID {
+	return uuid.MustParse(c.Subject)
 }
 
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(tokenSecret), nil
-	})
+	claims, err := ParseJWT(tokenString, tokenSecret)
 	if err != nil {
 		return uuid.Nil, err
 	}
-	claims, ok := token.Claims.(*jwt.RegisteredClaims)
-	if !ok || !token.Valid {
-		return uuid.Nil, jwt.ErrTokenInvalidClaims
+	return claims.UserID(), nil
+}
+
+// HasRole reports whether role grants any of the wanted roles. Admin
+// grants every role.
+func HasRole(role string, wanted ...string) bool {
+	if role == RoleAdmin {
+		return true
 	}
-	userID, err := uuid.Parse(claims.Subject)
-	if err != nil {
-		return uuid.Nil, jwt.ErrTokenInvalidSubject
+	for _, w := range wanted {
+		if role == w {
+			return true
+		}
 	}
-	return userID, nil
+	return false
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
@@ -70,6 +102,12 @@ func GetApiKey(headers http.Header) (string, error) {
 }
 
 func MakeRefreshToken() (string, error) {
+	return MakeToken()
+}
+
+// MakeToken returns 32 random bytes, hex encoded, for use as an opaque
+// single-purpose token.
+func MakeToken() (string, error) {
 	key := make([]byte, 32)
 	_, err := rand.Read(key)
 	if err != nil {
@@ -77,3 +115,10 @@ func MakeRefreshToken() (string, error) {
 	}
 	return hex.EncodeToString(key), nil
 }
+
+// HashToken returns the hex encoded SHA-256 digest of token. Opaque tokens
+// are stored as digests so a database leak doesn't expose usable tokens.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -176,7 +176,7 @@ func TestMakeJWT(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			token, err := MakeJWT(tt.userID, tt.tokenSecret, tt.expiresIn)
+			token, err := MakeJWT(tt.userID, RoleUser, tt.tokenSecret, tt.expiresIn)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("MakeJWT() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -200,19 +200,19 @@ func TestValidateJWT(t *testing.T) {
 	tokenSecret := "test-secret-key"
 
 	// Create a valid token for testing
-	validToken, err := MakeJWT(userID, tokenSecret, time.Hour)
+	validToken, err := MakeJWT(userID, RoleUser, tokenSecret, time.Hour)
 	if err != nil {
 		t.Fatalf("Failed to create valid token for testing: %v", err)
 	}
 
 	// Create an expired token
-	expiredToken, err := MakeJWT(userID, tokenSecret, -time.Hour)
+	expiredToken, err := MakeJWT(userID, RoleUser, tokenSecret, -time.Hour)
 	if err != nil {
 		t.Fatalf("Failed to create expired token for testing: %v", err)
 	}
 
 	// Create a token with different secret
-	differentSecretToken, err := MakeJWT(userID, "different-secret", time.Hour)
+	differentSecretToken, err := MakeJWT(userID, RoleUser, "different-secret", time.Hour)
 	if err != nil {
 		t.Fatalf("Failed to create token with different secret for testing: %v", err)
 	}
@@ -295,7 +295,7 @@ func TestJWTRoundtrip(t *testing.T) {
 	expiresIn := time.Hour
 
 	// Create a token
-	token, err := MakeJWT(userID, tokenSecret, expiresIn)
+	token, err := MakeJWT(userID, RoleUser, tokenSecret, expiresIn)
 	if err != nil {
 		t.Fatalf("MakeJWT() error = %v", err)
 	}
@@ -316,7 +316,7 @@ func TestJWTClaims(t *testing.T) {
 	tokenSecret := "test-secret-key"
 	expiresIn := time.Hour
 
-	token, err := MakeJWT(userID, tokenSecret, expiresIn)
+	token, err := MakeJWT(userID, RoleUser, tokenSecret, expiresIn)
 	if err != nil {
 		t.Fatalf("MakeJWT() error = %v", err)
 	}
@@ -366,6 +366,67 @@ func TestJWTClaims(t *testing.T) {
 	}
 }
 
+func TestParseJWTRole(t *testing.T) {
+	userID := uuid.New()
+	tokenSecret := "test-secret-key"
+
+	adminToken, err := MakeJWT(userID, RoleAdmin, tokenSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT() error = %v", err)
+	}
+	claims, err := ParseJWT(adminToken, tokenSecret)
+	if err != nil {
+		t.Fatalf("ParseJWT() error = %v", err)
+	}
+	if claims.Role != RoleAdmin {
+		t.Errorf("ParseJWT() role = %q, want %q", claims.Role, RoleAdmin)
+	}
+	if claims.UserID() != userID {
+		t.Errorf("ParseJWT() userID = %v, want %v", claims.UserID(), userID)
+	}
+
+	// Tokens issued before roles existed have no role claim.
+	legacyToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Issuer:    "chirpy",
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		Subject:   userID.String(),
+	}).SignedString([]byte(tokenSecret))
+	if err != nil {
+		t.Fatalf("failed to sign legacy token: %v", err)
+	}
+	claims, err = ParseJWT(legacyToken, tokenSecret)
+	if err != nil {
+		t.Fatalf("ParseJWT() error = %v", err)
+	}
+	if claims.Role != RoleUser {
+		t.Errorf("ParseJWT() role = %q, want %q", claims.Role, RoleUser)
+	}
+}
+
+func TestHasRole(t *testing.T) {
+	tests := []struct {
+		name   string
+		role   string
+		wanted []string
+		want   bool
+	}{
+		{name: "matching role", role: RoleModerator, wanted: []string{RoleModerator}, want: true},
+		{name: "one of several", role: RoleModerator, wanted: []string{RoleAdmin, RoleModerator}, want: true},
+		{name: "admin grants everything", role: RoleAdmin, wanted: []string{RoleModerator}, want: true},
+		{name: "user lacks moderator", role: RoleUser, wanted: []string{RoleModerator}, want: false},
+		{name: "moderator lacks admin", role: RoleModerator, wanted: []string{RoleAdmin}, want: false},
+		{name: "empty role", role: "", wanted: []string{RoleUser}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasRole(tt.role, tt.wanted...); got != tt.want {
+				t.Errorf("HasRole(%q, %v) = %v, want %v", tt.role, tt.wanted, got, tt.want)
+			}
+		})
+	}
+}
+
 func BenchmarkHashPassword(b *testing.B) {
 	password := "benchmarkpassword123"
 	for i := 0; i < b.N; i++ {
@@ -398,7 +459,7 @@ func BenchmarkMakeJWT(b *testing.B) {
 	expiresIn := time.Hour
 
 	for i := 0; i < b.N; i++ {
-		_, err := MakeJWT(userID, tokenSecret, expiresIn)
+		_, err := MakeJWT(userID, RoleUser, tokenSecret, expiresIn)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -409,7 +470,7 @@ func BenchmarkValidateJWT(b *testing.B) {
 	userID := uuid.New()
 	tokenSecret := "benchmark-secret"
 
-	token, err := MakeJWT(userID, tokenSecret, time.Hour)
+	token, err := MakeJWT(userID, RoleUser, tokenSecret, time.Hour)
 	if err != nil {
 		b.Fatal(err)
 	}
@@ -439,3 +500,25 @@ func TestGetBearerToken(t *testing.T) {
 		t.Errorf("Expected token 'token', got '%s'", token)
 	}
 }
+
+func TestHashToken(t *testing.T) {
+	token, err := MakeToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(token) != 64 {
+		t.Errorf("Expected a 64 character token, got %d characters", len(token))
+	}
+
+	hash := HashToken(token)
+	if hash == token {
+		t.Error("Expected the hash to differ from the token")
+	}
+	if hash != HashToken(token) {
+		t.Error("Expected hashing to be deterministic")
+	}
+	want := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	if got := HashToken("test"); got != want {
+		t.Errorf("Expected HashToken(\"test\") = %s, got %s", want, got)
+	}
+}
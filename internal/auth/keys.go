@@ -0,0 +1,282 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// minRSABits is the smallest RSA key accepted for RS256.
+const minRSABits = 2048
+
+// Key is a key that signs or verifies access tokens. Keys loaded from
+// public keys can only verify.
+type Key struct {
+	// ID is sent as the kid header of tokens the key signs.
+	ID     string
+	Method jwt.SigningMethod
+
+	signKey   crypto.PrivateKey
+	verifyKey crypto.PublicKey
+}
+
+// NewHMACKey returns an HS256 key. HS256 keys both sign and verify, and
+// are never published in the JWKS.
+func NewHMACKey(id string, secret []byte) *Key {
+	return &Key{ID: id, Method: jwt.SigningMethodHS256, signKey: secret, verifyKey: secret}
+}
+
+// CanSign reports whether the key holds private key material.
+func (k *Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+func (k *Key) public() bool {
+	_, ok := k.verifyKey.([]byte)
+	return !ok
+}
+
+// newKey wraps an RSA or Ed25519 key, private or public. Its ID is the
+// RFC 7638 thumbprint of the public key.
+func newKey(key any) (*Key, error) {
+	k := &Key{}
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		k.Method, k.signKey, k.verifyKey = jwt.SigningMethodRS256, key, &key.PublicKey
+	case *rsa.PublicKey:
+		k.Method, k.verifyKey = jwt.SigningMethodRS256, key
+	case ed25519.PrivateKey:
+		k.Method, k.signKey, k.verifyKey = jwt.SigningMethodEdDSA, key, key.Public()
+	case ed25519.PublicKey:
+		k.Method, k.verifyKey = jwt.SigningMethodEdDSA, key
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, want RSA or Ed25519", key)
+	}
+	if pub, ok := k.verifyKey.(*rsa.PublicKey); ok && pub.N.BitLen() < minRSABits {
+		return nil, fmt.Errorf("RSA key has %d bits, want at least %d", pub.N.BitLen(), minRSABits)
+	}
+	thumbprint, err := json.Marshal(k.jwk())
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(thumbprint)
+	k.ID = base64.RawURLEncoding.EncodeToString(sum[:])
+	return k, nil
+}
+
+// ParseKeyPEM parses an RSA or Ed25519 key from PEM. Private keys may be
+// PKCS #8 or PKCS #1; public keys PKIX or PKCS #1.
+func ParseKeyPEM(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	var key any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newKey(key)
+}
+
+// LoadKeyFile reads a PEM encoded key from path.
+func LoadKeyFile(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParseKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+// JWK is a public key in JSON Web Key form. Fields are in the order RFC
+// 7638 thumbprints need.
+type JWK struct {
+	Crv string `json:"crv,omitempty"`
+	E   string `json:"e,omitempty"`
+	Kty string `json:"kty"`
+	N   string `json:"n,omitempty"`
+	X   string `json:"x,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+}
+
+// jwk returns the required members of the key's JWK, without alg, kid and
+// use.
+func (k *Key) jwk() JWK {
+	switch pub := k.verifyKey.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		}
+	case ed25519.PublicKey:
+		return JWK{
+			Crv: "Ed25519",
+			Kty: "OKP",
+			X:   base64.RawURLEncoding.EncodeToString(pub),
+		}
+	}
+	return JWK{}
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// KeySet signs access tokens with one key and verifies them with any of
+// its keys, so old keys can keep verifying during a rotation.
+type KeySet struct {
+	signing    *Key
+	keys       map[string]*Key
+	order      []*Key
+	config     ValidatorConfig
+	algorithms map[string]bool
+}
+
+// NewKeySet returns a key set that signs with signing and verifies with
+// signing and every key in verifying, accepting tokens as config allows.
+// Key IDs must be unique.
+func NewKeySet(config ValidatorConfig, signing *Key, verifying ...*Key) (*KeySet, error) {
+	if signing == nil || !signing.CanSign() {
+		return nil, errors.New("signing key has no private key")
+	}
+	ks := &KeySet{
+		signing:    signing,
+		keys:       make(map[string]*Key),
+		config:     config,
+		algorithms: make(map[string]bool),
+	}
+	for _, key := range append([]*Key{signing}, verifying...) {
+		if _, ok := ks.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		ks.keys[key.ID] = key
+		ks.order = append(ks.order, key)
+		if len(config.Algorithms) == 0 {
+			ks.algorithms[key.Method.Alg()] = true
+		}
+	}
+	for _, alg := range config.Algorithms {
+		if !supportedAlgorithms[alg] {
+			return nil, fmt.Errorf("unsupported algorithm %q", alg)
+		}
+		ks.algorithms[alg] = true
+	}
+	if !ks.algorithms[signing.Method.Alg()] {
+		return nil, fmt.Errorf("signing key uses %s, which is not allowed", signing.Method.Alg())
+	}
+	return ks, nil
+}
+
+// MakeJWT issues an access token for userID.
+func (ks *KeySet) MakeJWT(userID uuid.UUID, role string, expiresIn time.Duration) (string, error) {
+	claims := Claims{
+		Role: role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    Issuer,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			Subject:   userID.String(),
+		},
+	}
+	if ks.config.Audience != "" {
+		claims.Audience = jwt.ClaimStrings{ks.config.Audience}
+	}
+	jwtToken := jwt.NewWithClaims(ks.signing.Method, claims)
+	if ks.signing.ID != "" {
+		jwtToken.Header["kid"] = ks.signing.ID
+	}
+	return jwtToken.SignedString(ks.signing.signKey)
+}
+
+// verifyKey picks the key for token by its kid header. The token's
+// algorithm must be allowed and be the key's, so a public key can never
+// be used as an HMAC secret.
+func (ks *KeySet) verifyKey(token *jwt.Token) (any, error) {
+	alg := token.Method.Alg()
+	if !ks.algorithms[alg] {
+		return nil, fmt.Errorf("%w: %s", ErrTokenAlgorithm, alg)
+	}
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrTokenUnknownKey, kid)
+	}
+	if alg != key.Method.Alg() {
+		return nil, fmt.Errorf("%w: key %q does not use %s", ErrTokenAlgorithm, kid, alg)
+	}
+	return key.verifyKey, nil
+}
+
+// ParseJWT validates an access token and returns its claims. Errors wrap
+// one of the ErrToken errors. Tokens issued before roles existed carry no
+// role claim and are treated as RoleUser.
+func (ks *KeySet) ParseJWT(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, ks.verifyKey, ks.config.parserOptions()...)
+	if err != nil {
+		return nil, tokenError(err)
+	}
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, ErrTokenClaims
+	}
+	if err := ks.config.checkAudience(claims, time.Now()); err != nil {
+		return nil, err
+	}
+	if _, err := uuid.Parse(claims.Subject); err != nil {
+		return nil, fmt.Errorf("%w: invalid subject", ErrTokenClaims)
+	}
+	if claims.Role == "" {
+		claims.Role = RoleUser
+	}
+	return claims, nil
+}
+
+// JWKS returns the public keys of the set. HMAC keys are secret and left
+// out.
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range ks.order {
+		if !key.public() {
+			continue
+		}
+		jwk := key.jwk()
+		jwk.Alg = key.Method.Alg()
+		jwk.Kid = key.ID
+		jwk.Use = "sig"
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
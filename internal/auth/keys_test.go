@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func mustPEM(t *testing.T, key any, private bool) []byte {
+	t.Helper()
+	var der []byte
+	var err error
+	blockType := "PUBLIC KEY"
+	if private {
+		blockType = "PRIVATE KEY"
+		der, err = x509.MarshalPKCS8PrivateKey(key)
+	} else {
+		der, err = x509.MarshalPKIXPublicKey(key)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+}
+
+func mustParseKey(t *testing.T, data []byte) *Key {
+	t.Helper()
+	key, err := ParseKeyPEM(data)
+	if err != nil {
+		t.Fatalf("ParseKeyPEM() error = %v", err)
+	}
+	return key
+}
+
+func mustKeySet(t *testing.T, signing *Key, verifying ...*Key) *KeySet {
+	t.Helper()
+	ks, err := NewKeySet(ValidatorConfig{}, signing, verifying...)
+	if err != nil {
+		t.Fatalf("NewKeySet() error = %v", err)
+	}
+	return ks
+}
+
+func TestKeySetRoundtrip(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		key  *Key
+		alg  string
+	}{
+		{"RS256", mustParseKey(t, mustPEM(t, rsaKey, true)), "RS256"},
+		{"RS256 PKCS1", mustParseKey(t, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})), "RS256"},
+		{"EdDSA", mustParseKey(t, mustPEM(t, edKey, true)), "EdDSA"},
+		{"HS256", NewHMACKey("", []byte("secret")), "HS256"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ks := mustKeySet(t, tt.key)
+			userID := uuid.New()
+			token, err := ks.MakeJWT(userID, RoleAdmin, time.Hour)
+			if err != nil {
+				t.Fatalf("MakeJWT() error = %v", err)
+			}
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parsed.Method.Alg() != tt.alg {
+				t.Errorf("alg = %s, want %s", parsed.Method.Alg(), tt.alg)
+			}
+			if kid, _ := parsed.Header["kid"].(string); kid != tt.key.ID {
+				t.Errorf("kid = %q, want %q", kid, tt.key.ID)
+			}
+			claims, err := ks.ParseJWT(token)
+			if err != nil {
+				t.Fatalf("ParseJWT() error = %v", err)
+			}
+			if claims.Subject != userID.String() || claims.Role != RoleAdmin {
+				t.Errorf("claims = %s %s, want %s %s", claims.Subject, claims.Role, userID, RoleAdmin)
+			}
+		})
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	_, oldKey, _ := ed25519.GenerateKey(rand.Reader)
+	_, newKey, _ := ed25519.GenerateKey(rand.Reader)
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	oldSigning := mustParseKey(t, mustPEM(t, oldKey, true))
+	oldVerifying := mustParseKey(t, mustPEM(t, oldKey.Public(), false))
+	if oldVerifying.CanSign() {
+		t.Error("public key CanSign() = true")
+	}
+	if oldVerifying.ID != oldSigning.ID {
+		t.Errorf("public key ID = %q, private key ID = %q", oldVerifying.ID, oldSigning.ID)
+	}
+
+	oldToken, err := mustKeySet(t, oldSigning).MakeJWT(uuid.New(), RoleUser, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherToken, err := mustKeySet(t, mustParseKey(t, mustPEM(t, otherKey, true))).MakeJWT(uuid.New(), RoleUser, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ks := mustKeySet(t, mustParseKey(t, mustPEM(t, newKey, true)), oldVerifying)
+	if _, err := ks.ParseJWT(oldToken); err != nil {
+		t.Errorf("ParseJWT() of token signed by retired key error = %v", err)
+	}
+	if _, err := ks.ParseJWT(otherToken); err == nil {
+		t.Error("ParseJWT() accepted token signed by unknown key")
+	}
+	if _, err := NewKeySet(ValidatorConfig{}, oldVerifying); err == nil {
+		t.Error("NewKeySet() accepted public signing key")
+	}
+	if _, err := NewKeySet(ValidatorConfig{}, oldSigning, oldVerifying); err == nil {
+		t.Error("NewKeySet() accepted duplicate key id")
+	}
+}
+
+func TestKeySetRejectsAlgorithmConfusion(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicPEM := mustPEM(t, &rsaKey.PublicKey, false)
+	key := mustParseKey(t, publicPEM)
+	ks := mustKeySet(t, mustParseKey(t, mustPEM(t, rsaKey, true)))
+
+	// An attacker who knows the public key signs an HS256 token with it.
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		Role: RoleAdmin,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   uuid.New().String(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+	forged.Header["kid"] = key.ID
+	token, err := forged.SignedString(publicPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.ParseJWT(token); err == nil {
+		t.Error("ParseJWT() accepted HS256 token for an RS256 key")
+	}
+}
+
+func TestParseKeyPEMErrors(t *testing.T) {
+	smallKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not PEM", []byte("secret")},
+		{"unknown block", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{1}})},
+		{"garbage", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte{1}})},
+		{"short RSA key", mustPEM(t, smallKey, true)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseKeyPEM(tt.data); err == nil {
+				t.Error("ParseKeyPEM() error = nil")
+			}
+		})
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	// RFC 8037 appendix A.
+	seed, err := base64.RawURLEncoding.DecodeString("nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	edKey := mustParseKey(t, mustPEM(t, ed25519.NewKeyFromSeed(seed), true))
+	if want := "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k"; edKey.ID != want {
+		t.Errorf("ID = %q, want RFC 7638 thumbprint %q", edKey.ID, want)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPublic := mustParseKey(t, mustPEM(t, &rsaKey.PublicKey, false))
+
+	set := mustKeySet(t, edKey, NewHMACKey("legacy", []byte("secret")), rsaPublic).JWKS()
+	if len(set.Keys) != 2 {
+		t.Fatalf("JWKS() has %d keys, want 2 without the HMAC key", len(set.Keys))
+	}
+	ed := set.Keys[0]
+	if ed.Kty != "OKP" || ed.Crv != "Ed25519" || ed.Alg != "EdDSA" || ed.Use != "sig" || ed.Kid != edKey.ID {
+		t.Errorf("Ed25519 JWK = %+v", ed)
+	}
+	if ed.X != "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo" {
+		t.Errorf("Ed25519 JWK x = %q", ed.X)
+	}
+	rs := set.Keys[1]
+	if rs.Kty != "RSA" || rs.Alg != "RS256" || rs.E != "AQAB" || rs.Kid != rsaPublic.ID || rs.N == "" {
+		t.Errorf("RSA JWK = %+v", rs)
+	}
+}
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Issuer is the iss claim of every access token. Tokens from any other
+// issuer are rejected.
+const Issuer = "chirpy"
+
+// Errors returned when an access token is rejected. Each is wrapped
+// together with the underlying error, so test for them with errors.Is.
+var (
+	ErrTokenMalformed   = errors.New("malformed token")
+	ErrTokenAlgorithm   = errors.New("token signing algorithm not allowed")
+	ErrTokenUnknownKey  = errors.New("token signed by unknown key")
+	ErrTokenSignature   = errors.New("invalid token signature")
+	ErrTokenExpired     = errors.New("token expired")
+	ErrTokenNotValidYet = errors.New("token not valid yet")
+	ErrTokenIssuer      = errors.New("invalid token issuer")
+	ErrTokenAudience    = errors.New("invalid token audience")
+	ErrTokenClaims      = errors.New("invalid token claims")
+)
+
+// supportedAlgorithms are the signing algorithms keys can use.
+var supportedAlgorithms = map[string]bool{
+	jwt.SigningMethodHS256.Alg(): true,
+	jwt.SigningMethodRS256.Alg(): true,
+	jwt.SigningMethodEdDSA.Alg(): true,
+}
+
+// ValidatorConfig controls which access tokens a KeySet accepts.
+type ValidatorConfig struct {
+	// Audience is put in the aud claim of issued tokens and required in
+	// the aud claim of parsed ones. Empty means tokens carry no audience.
+	Audience string
+	// Algorithms are the only signing algorithms accepted. Empty means
+	// the algorithms of the set's keys.
+	Algorithms []string
+	// Leeway is the clock skew allowed when checking exp, nbf and iat.
+	Leeway time.Duration
+	// AllowMissingAudienceUntil is the time until which tokens with no aud
+	// claim are still accepted, so tokens issued before Audience was set
+	// keep working until they expire. The zero time rejects them.
+	AllowMissingAudienceUntil time.Time
+}
+
+// parserOptions returns the jwt parser options enforcing the config.
+// Algorithms are checked by KeySet.verifyKey, before the signature, and
+// the audience by checkAudience, so a missing aud claim is reported as
+// ErrTokenAudience.
+func (c ValidatorConfig) parserOptions() []jwt.ParserOption {
+	return []jwt.ParserOption{
+		jwt.WithIssuer(Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(c.Leeway),
+	}
+}
+
+// checkAudience reports whether claims are meant for the configured
+// audience at time now.
+func (c ValidatorConfig) checkAudience(claims *Claims, now time.Time) error {
+	if c.Audience == "" || slices.Contains(claims.Audience, c.Audience) {
+		return nil
+	}
+	if len(claims.Audience) == 0 && now.Before(c.AllowMissingAudienceUntil) {
+		return nil
+	}
+	return fmt.Errorf("%w: want %q, got %q", ErrTokenAudience, c.Audience, []string(claims.Audience))
+}
+
+// tokenError maps an error from the jwt package to one of the ErrToken
+// errors.
+func tokenError(err error) error {
+	kinds := []struct {
+		jwtErr, err error
+	}{
+		{jwt.ErrTokenMalformed, ErrTokenMalformed},
+		{ErrTokenAlgorithm, nil},
+		{ErrTokenUnknownKey, nil},
+		{jwt.ErrTokenSignatureInvalid, ErrTokenSignature},
+		{jwt.ErrTokenExpired, ErrTokenExpired},
+		{jwt.ErrTokenNotValidYet, ErrTokenNotValidYet},
+		{jwt.ErrTokenUsedBeforeIssued, ErrTokenNotValidYet},
+		{jwt.ErrTokenInvalidIssuer, ErrTokenIssuer},
+	}
+	for _, kind := range kinds {
+		if errors.Is(err, kind.jwtErr) {
+			if kind.err == nil {
+				// Already one of ours, returned by verifyKey.
+				return err
+			}
+			return fmt.Errorf("%w: %w", kind.err, err)
+		}
+	}
+	return fmt.Errorf("%w: %w", ErrTokenClaims, err)
+}
@@ -0,0 +1,275 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// signClaims signs claims with key, bypassing KeySet.MakeJWT so tests can
+// build tokens it would never issue.
+func signClaims(t *testing.T, key *Key, method jwt.SigningMethod, claims Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	s, err := token.SignedString(key.signKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func validClaims() Claims {
+	return Claims{
+		Role: RoleUser,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    Issuer,
+			Audience:  jwt.ClaimStrings{"chirpy-api"},
+			Subject:   uuid.New().String(),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+}
+
+func TestParseJWTErrors(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := mustParseKey(t, mustPEM(t, edKey, true))
+	hmacKey := NewHMACKey("legacy", []byte("secret"))
+	config := ValidatorConfig{
+		Audience:   "chirpy-api",
+		Algorithms: []string{"EdDSA"},
+		Leeway:     30 * time.Second,
+	}
+	ks, err := NewKeySet(config, key, hmacKey)
+	if err != nil {
+		t.Fatalf("NewKeySet() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		token  func() string
+		want   error
+		wantOK bool
+	}{
+		{
+			name:   "valid",
+			token:  func() string { return signClaims(t, key, jwt.SigningMethodEdDSA, validClaims()) },
+			wantOK: true,
+		},
+		{
+			name: "expired within leeway",
+			token: func() string {
+				c := validClaims()
+				c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-10 * time.Second))
+				return signClaims(t, key, jwt.SigningMethodEdDSA, c)
+			},
+			wantOK: true,
+		},
+		{
+			name: "expired",
+			token: func() string {
+				c := validClaims()
+				c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+				return signClaims(t, key, jwt.SigningMethodEdDSA, c)
+			},
+			want: ErrTokenExpired,
+		},
+		{
+			name: "issued in the future",
+			token: func() string {
+				c := validClaims()
+				c.IssuedAt = jwt.NewNumericDate(time.Now().Add(time.Minute))
+				return signClaims(t, key, jwt.SigningMethodEdDSA, c)
+			},
+			want: ErrTokenNotValidYet,
+		},
+		{
+			name: "no expiry",
+			token: func() string {
+				c := validClaims()
+				c.ExpiresAt = nil
+				return signClaims(t, key, jwt.SigningMethodEdDSA, c)
+			},
+			want: ErrTokenClaims,
+		},
+		{
+			name: "wrong issuer",
+			token: func() string {
+				c := validClaims()
+				c.Issuer = "someone-else"
+				return signClaims(t, key, jwt.SigningMethodEdDSA, c)
+			},
+			want: ErrTokenIssuer,
+		},
+		{
+			name: "wrong audience",
+			token: func() string {
+				c := validClaims()
+				c.Audience = jwt.ClaimStrings{"billing"}
+				return signClaims(t, key, jwt.SigningMethodEdDSA, c)
+			},
+			want: ErrTokenAudience,
+		},
+		{
+			name: "no audience",
+			token: func() string {
+				c := validClaims()
+				c.Audience = nil
+				return signClaims(t, key, jwt.SigningMethodEdDSA, c)
+			},
+			want: ErrTokenAudience,
+		},
+		{
+			name:  "algorithm not allowed",
+			token: func() string { return signClaims(t, hmacKey, jwt.SigningMethodHS256, validClaims()) },
+			want:  ErrTokenAlgorithm,
+		},
+		{
+			name: "unsigned",
+			token: func() string {
+				s, err := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return s
+			},
+			want: ErrTokenAlgorithm,
+		},
+		{
+			name: "unknown key",
+			token: func() string {
+				_, other, _ := ed25519.GenerateKey(rand.Reader)
+				return signClaims(t, mustParseKey(t, mustPEM(t, other, true)), jwt.SigningMethodEdDSA, validClaims())
+			},
+			want: ErrTokenUnknownKey,
+		},
+		{
+			name: "bad signature",
+			token: func() string {
+				_, other, _ := ed25519.GenerateKey(rand.Reader)
+				forger := mustParseKey(t, mustPEM(t, other, true))
+				forger.ID = key.ID
+				return signClaims(t, forger, jwt.SigningMethodEdDSA, validClaims())
+			},
+			want: ErrTokenSignature,
+		},
+		{
+			name: "invalid subject",
+			token: func() string {
+				c := validClaims()
+				c.Subject = "admin"
+				return signClaims(t, key, jwt.SigningMethodEdDSA, c)
+			},
+			want: ErrTokenClaims,
+		},
+		{
+			name:  "malformed",
+			token: func() string { return "not.a.jwt" },
+			want:  ErrTokenMalformed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ks.ParseJWT(tt.token())
+			if tt.wantOK {
+				if err != nil {
+					t.Errorf("ParseJWT() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("ParseJWT() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeySetAudience(t *testing.T) {
+	key := NewHMACKey("", []byte("secret"))
+	ks, err := NewKeySet(ValidatorConfig{Audience: "chirpy-api"}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := ks.MakeJWT(uuid.New(), RoleUser, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := ks.ParseJWT(token)
+	if err != nil {
+		t.Fatalf("ParseJWT() error = %v", err)
+	}
+	if len(claims.Audience) != 1 || claims.Audience[0] != "chirpy-api" {
+		t.Errorf("Audience = %v, want [chirpy-api]", claims.Audience)
+	}
+	other, err := NewKeySet(ValidatorConfig{Audience: "billing"}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.ParseJWT(token); !errors.Is(err, ErrTokenAudience) {
+		t.Errorf("ParseJWT() for another audience error = %v, want %v", err, ErrTokenAudience)
+	}
+}
+
+func TestCheckAudienceTransition(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	config := ValidatorConfig{
+		Audience:                  "chirpy-api",
+		AllowMissingAudienceUntil: now.Add(time.Hour),
+	}
+	tests := []struct {
+		name     string
+		audience jwt.ClaimStrings
+		now      time.Time
+		wantErr  bool
+	}{
+		{name: "configured audience", audience: jwt.ClaimStrings{"chirpy-api"}, now: now},
+		{name: "missing audience in window", now: now},
+		{name: "missing audience after window", now: now.Add(time.Hour), wantErr: true},
+		{name: "wrong audience in window", audience: jwt.ClaimStrings{"billing"}, now: now, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			claims := &Claims{RegisteredClaims: jwt.RegisteredClaims{Audience: tc.audience}}
+			err := config.checkAudience(claims, tc.now)
+			if tc.wantErr && !errors.Is(err, ErrTokenAudience) {
+				t.Errorf("checkAudience() error = %v, want %v", err, ErrTokenAudience)
+			}
+			if !tc.wantErr && err != nil {
+				t.Errorf("checkAudience() error = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestNewKeySetAlgorithms(t *testing.T) {
+	key := NewHMACKey("", []byte("secret"))
+	tests := []struct {
+		name       string
+		algorithms []string
+		wantErr    bool
+	}{
+		{"default", nil, false},
+		{"signing algorithm", []string{"HS256", "EdDSA"}, false},
+		{"signing algorithm not allowed", []string{"EdDSA"}, true},
+		{"unsupported", []string{"HS256", "none"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeySet(ValidatorConfig{Algorithms: tt.algorithms}, key)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewKeySet() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+type entry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// Cache is a map whose entries expire a fixed time after they were set.
+// It is safe for concurrent use. Expired entries are dropped when they
+// are next read or when the cache grows past its sweep threshold.
+type Cache[K comparable, V any] struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[K]entry[V]
+	// sweepAt is the size at which the next Set removes expired entries.
+	sweepAt int
+}
+
+const minSweepSize = 1024
+
+func New[K comparable, V any](ttl time.Duration) *Cache[K, V] {
+	return NewWithClock[K, V](ttl, time.Now)
+}
+
+// NewWithClock is like New but reads the current time from now.
+func NewWithClock[K comparable, V any](ttl time.Duration, now func() time.Time) *Cache[K, V] {
+	return &Cache[K, V]{
+		ttl:     ttl,
+		now:     now,
+		entries: make(map[K]entry[V]),
+		sweepAt: minSweepSize,
+	}
+}
+
+// Get returns the value stored for key if it has not expired.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	if !c.now().Before(e.expiresAt) {
+		delete(c.entries, key)
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if len(c.entries) >= c.sweepAt {
+		for k, e := range c.entries {
+			if !now.Before(e.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		c.sweepAt = max(minSweepSize, 2*len(c.entries))
+	}
+	c.entries[key] = entry[V]{value: value, expiresAt: now.Add(c.ttl)}
+}
+
+func (c *Cache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
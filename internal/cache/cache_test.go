@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (f *fakeClock) now() time.Time {
+	return f.t
+}
+
+func TestCacheGetSet(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	c := NewWithClock[string, int](time.Minute, clock.now)
+
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("Get() on empty cache returned a value")
+	}
+	c.Set("a", 1)
+	if got, ok := c.Get("a"); !ok || got != 1 {
+		t.Errorf("Get() = %v, %v, want 1, true", got, ok)
+	}
+
+	clock.t = clock.t.Add(59 * time.Second)
+	if _, ok := c.Get("a"); !ok {
+		t.Error("Get() before expiry returned nothing")
+	}
+
+	clock.t = clock.t.Add(time.Second)
+	if _, ok := c.Get("a"); ok {
+		t.Error("Get() at expiry returned a value")
+	}
+	if c.Len() != 0 {
+		t.Errorf("Len() = %d after expired Get, want 0", c.Len())
+	}
+}
+
+func TestCacheSetRefreshesExpiry(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	c := NewWithClock[string, int](time.Minute, clock.now)
+
+	c.Set("a", 1)
+	clock.t = clock.t.Add(30 * time.Second)
+	c.Set("a", 2)
+	clock.t = clock.t.Add(45 * time.Second)
+	if got, ok := c.Get("a"); !ok || got != 2 {
+		t.Errorf("Get() = %v, %v, want 2, true", got, ok)
+	}
+}
+
+func TestCacheDelete(t *testing.T) {
+	c := New[string, int](time.Minute)
+	c.Set("a", 1)
+	c.Delete("a")
+	if _, ok := c.Get("a"); ok {
+		t.Error("Get() after Delete returned a value")
+	}
+}
+
+func TestCacheSweepsExpiredEntries(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	c := NewWithClock[int, int](time.Minute, clock.now)
+
+	for i := 0; i < minSweepSize; i++ {
+		c.Set(i, i)
+	}
+	clock.t = clock.t.Add(time.Hour)
+	c.Set(-1, -1)
+	if c.Len() != 1 {
+		t.Errorf("Len() = %d after sweep, want 1", c.Len())
+	}
+}
@@ -0,0 +1,124 @@
+package chirptext
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	maxHashtagLength = 64
+	minHandleLength  = 3
+	maxHandleLength  = 30
+)
+
+// Mention is an @handle found in a chirp body. Start and End are rune
+// offsets into the body, End exclusive, and cover the leading '@'.
+type Mention struct {
+	Handle string
+	Start  int
+	End    int
+}
+
+// Hashtags returns the normalized hashtags found in body, in order of first
+// appearance and without duplicates. A hashtag is a '#' that does not follow
+// a letter, digit or underscore, followed by letters, digits and
+// underscores, at least one of which is not a digit. Tags are lowercased.
+func Hashtags(body string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for i := 0; i < len(body); {
+		r, size := utf8.DecodeRuneInString(body[i:])
+		if r != '#' || (i > 0 && isWordRune(lastRune(body[:i]))) {
+			i += size
+			continue
+		}
+		end := i + size
+		hasLetter := false
+		for end < len(body) {
+			r, size := utf8.DecodeRuneInString(body[end:])
+			if !isWordRune(r) {
+				break
+			}
+			if !unicode.IsDigit(r) {
+				hasLetter = true
+			}
+			end += size
+		}
+		tag := strings.ToLower(body[i+1 : end])
+		if hasLetter && utf8.RuneCountInString(tag) <= maxHashtagLength && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+		i = end
+	}
+	return tags
+}
+
+// NormalizeHashtag turns user input such as "#Go" into the stored form "go".
+func NormalizeHashtag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(tag, "#"))
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func lastRune(s string) rune {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r
+}
+
+// Mentions returns every @handle in body in order of appearance. An '@'
+// that follows a letter, digit or underscore, as in an email address, does
+// not start a mention. A mention runs over every following letter, digit
+// or underscore, including non-ASCII ones, so "@zoë" is never cut short to
+// "zo". Handles are lowercased; their validity against ValidHandle is left
+// to the caller.
+func Mentions(body string) []Mention {
+	var mentions []Mention
+	runes := []rune(body)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '@' || (i > 0 && isWordRune(runes[i-1])) {
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+		if end == i+1 {
+			continue
+		}
+		mentions = append(mentions, Mention{
+			Handle: strings.ToLower(string(runes[i+1 : end])),
+			Start:  i,
+			End:    end,
+		})
+		i = end - 1
+	}
+	return mentions
+}
+
+// NormalizeHandle turns user input such as "@Alice" into the stored form
+// "alice".
+func NormalizeHandle(handle string) string {
+	return strings.ToLower(strings.TrimPrefix(handle, "@"))
+}
+
+// ValidHandle reports whether handle, already normalized, is 3 to 30 ASCII
+// letters, digits or underscores.
+func ValidHandle(handle string) bool {
+	if len(handle) < minHandleLength || len(handle) > maxHandleLength {
+		return false
+	}
+	for _, r := range handle {
+		if !isHandleRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isHandleRune(r rune) bool {
+	return r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9')
+}
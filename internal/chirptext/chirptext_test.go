@@ -0,0 +1,163 @@
+package chirptext
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHashtags(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{
+			name: "no hashtags",
+			body: "just a chirp",
+			want: nil,
+		},
+		{
+			name: "single hashtag",
+			body: "learning #golang today",
+			want: []string{"golang"},
+		},
+		{
+			name: "normalized to lowercase",
+			body: "#GoLang rocks",
+			want: []string{"golang"},
+		},
+		{
+			name: "duplicates removed",
+			body: "#go #Go #GO",
+			want: []string{"go"},
+		},
+		{
+			name: "punctuation ends the tag",
+			body: "#go, #sql! (#http)",
+			want: []string{"go", "sql", "http"},
+		},
+		{
+			name: "numeric only is not a tag",
+			body: "we're #1",
+			want: nil,
+		},
+		{
+			name: "digits allowed with letters",
+			body: "#web3 and #2024goals",
+			want: []string{"web3", "2024goals"},
+		},
+		{
+			name: "hash inside a word is ignored",
+			body: "c#sharp and issue#12",
+			want: nil,
+		},
+		{
+			name: "bare hash",
+			body: "# nothing",
+			want: nil,
+		},
+		{
+			name: "unicode letters",
+			body: "#café au lait",
+			want: []string{"café"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Hashtags(tt.body)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Hashtags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeHashtag(t *testing.T) {
+	if got := NormalizeHashtag("#GoLang"); got != "golang" {
+		t.Errorf("NormalizeHashtag() = %q, want %q", got, "golang")
+	}
+	if got := NormalizeHashtag("sql"); got != "sql" {
+		t.Errorf("NormalizeHashtag() = %q, want %q", got, "sql")
+	}
+}
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []Mention
+	}{
+		{
+			name: "no mentions",
+			body: "hello world",
+			want: nil,
+		},
+		{
+			name: "single mention",
+			body: "hi @alice!",
+			want: []Mention{{Handle: "alice", Start: 3, End: 9}},
+		},
+		{
+			name: "normalized to lowercase",
+			body: "@Bob_99 said so",
+			want: []Mention{{Handle: "bob_99", Start: 0, End: 7}},
+		},
+		{
+			name: "repeated mentions keep every span",
+			body: "@al @al",
+			want: []Mention{{Handle: "al", Start: 0, End: 3}, {Handle: "al", Start: 4, End: 7}},
+		},
+		{
+			name: "email address is not a mention",
+			body: "mail me at bob@example.com",
+			want: nil,
+		},
+		{
+			name: "bare at sign",
+			body: "meet @ noon",
+			want: nil,
+		},
+		{
+			name: "offsets count runes",
+			body: "café @bob",
+			want: []Mention{{Handle: "bob", Start: 5, End: 9}},
+		},
+		{
+			name: "non-ASCII handle is scanned whole",
+			body: "café @Zoë, hi",
+			want: []Mention{{Handle: "zoë", Start: 5, End: 9}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Mentions(tt.body)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Mentions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidHandle(t *testing.T) {
+	tests := []struct {
+		handle string
+		want   bool
+	}{
+		{handle: "alice", want: true},
+		{handle: "bob_99", want: true},
+		{handle: "al", want: false},
+		{handle: strings.Repeat("a", 31), want: false},
+		{handle: "has space", want: false},
+		{handle: "zoë", want: false},
+		{handle: "", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidHandle(tt.handle); got != tt.want {
+			t.Errorf("ValidHandle(%q) = %v, want %v", tt.handle, got, tt.want)
+		}
+	}
+}
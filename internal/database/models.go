@@ -17,22 +17,185 @@ type Chirp struct {
 	UpdatedAt time.Time
 	Body      string
 	UserID    uuid.UUID
+	InReplyTo uuid.NullUUID
+	QuoteOf   uuid.NullUUID
+	HiddenAt  sql.NullTime
 }
 
-type RefreshToken struct {
-	Token     string
+type ChirpHashtag struct {
+	ChirpID   uuid.UUID
+	Tag       string
+	CreatedAt time.Time
+}
+
+type ChirpLike struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpMention struct {
+	ChirpID    uuid.UUID
+	UserID     uuid.UUID
+	Handle     string
+	StartIndex int32
+	EndIndex   int32
+	CreatedAt  time.Time
+}
+
+type ChirpRevision struct {
+	ID        uuid.UUID
+	ChirpID   uuid.UUID
+	Body      string
+	CreatedAt time.Time
+}
+
+type EmailVerificationToken struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Email     string
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type LoginChallenge struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	TokenHash string
+	ExpiresAt time.Time
+	Attempts  int32
+	UsedAt    sql.NullTime
+}
+
+type LoginFailure struct {
+	Subject      string
+	Failures     int32
+	LastFailedAt time.Time
+	BlockedUntil sql.NullTime
+}
+
+type LoginLockout struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	Kind        string
+	Email       string
+	UserID      uuid.NullUUID
+	Ip          string
+	Failures    int32
+	LockedUntil time.Time
+}
+
+type ModerationDecision struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	ChirpID     uuid.UUID
+	ChirpUserID uuid.UUID
+	ChirpBody   string
+	Action      string
+	Note        string
+}
+
+type ModerationRule struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Pattern     string
+	MatchType   string
+	Action      string
+	Replacement string
+}
+
+type PasswordResetToken struct {
+	ID        uuid.UUID
 	CreatedAt time.Time
-	UpdatedAt time.Time
 	UserID    uuid.UUID
+	TokenHash string
 	ExpiresAt time.Time
-	RevokedAt sql.NullTime
+	UsedAt    sql.NullTime
+}
+
+type Rechirp struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type RefreshToken struct {
+	TokenHash  string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	ExpiresAt  time.Time
+	RevokedAt  sql.NullTime
+	FamilyID   uuid.UUID
+	RotatedAt  sql.NullTime
+	UserAgent  string
+	Ip         string
+	LastUsedAt time.Time
+}
+
+type Report struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	ChirpID    uuid.UUID
+	ReporterID uuid.NullUUID
+	Reason     string
+	Details    string
+	ResolvedAt sql.NullTime
+	DecisionID uuid.NullUUID
+}
+
+type TotpRecoveryCode struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	CodeHash  string
+	UsedAt    sql.NullTime
 }
 
 type User struct {
-	ID             uuid.UUID
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	ChirpyRed      sql.NullBool
+	ID                    uuid.UUID
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+	Email                 string
+	HashedPassword        string
+	ChirpyRed             sql.NullBool
+	Handle                sql.NullString
+	Role                  string
+	SuspendedAt           sql.NullTime
+	PasswordResetRequired bool
+	SuspensionReason      string
+	SuspendedUntil        sql.NullTime
+	EmailVerifiedAt       sql.NullTime
+	TotpSecret            sql.NullString
+	TotpPendingSecret     sql.NullString
+	TotpEnabledAt         sql.NullTime
+	TotpLastStep          int64
+}
+
+type UserBlock struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
+type UserMute struct {
+	MuterID   uuid.UUID
+	MutedID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type ViewerHiddenAuthor struct {
+	ViewerID uuid.UUID
+	AuthorID uuid.UUID
 }
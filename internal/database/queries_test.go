@@ -0,0 +1,30 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestFeedsHideMutedAndBlockedAuthors guards the feed queries against
+// losing the viewer_hidden_authors filter. Every branch of a UNION must
+// apply it, otherwise rechirps or own chirps leak muted authors back in.
+func TestFeedsHideMutedAndBlockedAuthors(t *testing.T) {
+	feeds := map[string]string{
+		"ListChirpsAsc":         listChirpsAsc,
+		"ListChirpsDesc":        listChirpsDesc,
+		"ListAuthorEntriesAsc":  listAuthorEntriesAsc,
+		"ListAuthorEntriesDesc": listAuthorEntriesDesc,
+		"ListTimelineEntries":   listTimelineEntries,
+		"ListMentionedChirps":   listMentionedChirps,
+	}
+
+	for name, query := range feeds {
+		t.Run(name, func(t *testing.T) {
+			for i, branch := range strings.Split(query, "UNION ALL") {
+				if !strings.Contains(branch, "viewer_hidden_authors") {
+					t.Errorf("branch %d does not exclude viewer_hidden_authors:\n%s", i, branch)
+				}
+			}
+		})
+	}
+}
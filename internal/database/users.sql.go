@@ -11,17 +11,71 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const confirmUserEmail = `-- name: ConfirmUserEmail :one
+UPDATE users
+SET email = $1,
+    email_verified_at = NOW(),
+    updated_at = NOW()
+WHERE id = $2
+RETURNING id, created_at, updated_at, email, hashed_password, chirpy_red, handle, role, suspended_at, password_reset_required, suspension_reason, suspended_until, email_verified_at, totp_secret, totp_pending_secret, totp_enabled_at, totp_last_step
+`
+
+type ConfirmUserEmailParams struct {
+	Email string
+	ID    uuid.UUID
+}
+
+func (q *Queries) ConfirmUserEmail(ctx context.Context, arg ConfirmUserEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, confirmUserEmail, arg.Email, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.ChirpyRed,
+		&i.Handle,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.PasswordResetRequired,
+		&i.SuspensionReason,
+		&i.SuspendedUntil,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpPendingSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const countUsersWithRole = `-- name: CountUsersWithRole :one
+SELECT COUNT(*)
+FROM users
+WHERE role = $1
+`
+
+func (q *Queries) CountUsersWithRole(ctx context.Context, role string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUsersWithRole, role)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
-VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2)
-RETURNING id, created_at, updated_at, email, chirpy_red
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3)
+RETURNING id, created_at, updated_at, email, chirpy_red, handle
 `
 
 type CreateUserParams struct {
 	Email          string
 	HashedPassword string
+	Handle         sql.NullString
 }
 
 type CreateUserRow struct {
@@ -30,10 +84,11 @@ type CreateUserRow struct {
 	UpdatedAt time.Time
 	Email     string
 	ChirpyRed sql.NullBool
+	Handle    sql.NullString
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateUserRow, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword, arg.Handle)
 	var i CreateUserRow
 	err := row.Scan(
 		&i.ID,
@@ -41,6 +96,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateU
 		&i.UpdatedAt,
 		&i.Email,
 		&i.ChirpyRed,
+		&i.Handle,
 	)
 	return i, err
 }
@@ -54,8 +110,43 @@ func (q *Queries) DeleteAllUsers(ctx context.Context) error {
 	return err
 }
 
+const getUserAccountStatus = `-- name: GetUserAccountStatus :one
+SELECT
+    role,
+    (suspended_at IS NOT NULL AND (suspended_until IS NULL OR suspended_until > NOW()))::boolean AS suspended,
+    suspension_reason,
+    suspended_until,
+    password_reset_required,
+    (email_verified_at IS NOT NULL)::boolean AS email_verified
+FROM users
+WHERE id = $1
+`
+
+type GetUserAccountStatusRow struct {
+	Role                  string
+	Suspended             bool
+	SuspensionReason      string
+	SuspendedUntil        sql.NullTime
+	PasswordResetRequired bool
+	EmailVerified         bool
+}
+
+func (q *Queries) GetUserAccountStatus(ctx context.Context, id uuid.UUID) (GetUserAccountStatusRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserAccountStatus, id)
+	var i GetUserAccountStatusRow
+	err := row.Scan(
+		&i.Role,
+		&i.Suspended,
+		&i.SuspensionReason,
+		&i.SuspendedUntil,
+		&i.PasswordResetRequired,
+		&i.EmailVerified,
+	)
+	return i, err
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, chirpy_red
+SELECT id, created_at, updated_at, email, hashed_password, chirpy_red, handle, role, suspended_at, password_reset_required, suspension_reason, suspended_until, email_verified_at, totp_secret, totp_pending_secret, totp_enabled_at, totp_last_step
 FROM users
 WHERE email = $1
 `
@@ -70,12 +161,23 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.HashedPassword,
 		&i.ChirpyRed,
+		&i.Handle,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.PasswordResetRequired,
+		&i.SuspensionReason,
+		&i.SuspendedUntil,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpPendingSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, created_at, updated_at, email, hashed_password, chirpy_red
+SELECT id, created_at, updated_at, email, hashed_password, chirpy_red, handle, role, suspended_at, password_reset_required, suspension_reason, suspended_until, email_verified_at, totp_secret, totp_pending_secret, totp_enabled_at, totp_last_step
 FROM users
 WHERE id = $1
 `
@@ -90,19 +192,30 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.Email,
 		&i.HashedPassword,
 		&i.ChirpyRed,
+		&i.Handle,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.PasswordResetRequired,
+		&i.SuspensionReason,
+		&i.SuspendedUntil,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpPendingSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
 	)
 	return i, err
 }
 
 const getUserByRefreshToken = `-- name: GetUserByRefreshToken :one
-SELECT users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.chirpy_red
+SELECT users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.chirpy_red, users.handle, users.role, users.suspended_at, users.password_reset_required, users.suspension_reason, users.suspended_until, users.email_verified_at, users.totp_secret, users.totp_pending_secret, users.totp_enabled_at, users.totp_last_step
 FROM users
 JOIN refresh_tokens ON users.id = refresh_tokens.user_id
-WHERE refresh_tokens.token = $1
+WHERE refresh_tokens.token_hash = $1
 `
 
-func (q *Queries) GetUserByRefreshToken(ctx context.Context, token string) (User, error) {
-	row := q.db.QueryRowContext(ctx, getUserByRefreshToken, token)
+func (q *Queries) GetUserByRefreshToken(ctx context.Context, tokenHash string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByRefreshToken, tokenHash)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -111,23 +224,238 @@ func (q *Queries) GetUserByRefreshToken(ctx context.Context, token string) (User
 		&i.Email,
 		&i.HashedPassword,
 		&i.ChirpyRed,
+		&i.Handle,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.PasswordResetRequired,
+		&i.SuspensionReason,
+		&i.SuspendedUntil,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpPendingSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const getUsersByHandles = `-- name: GetUsersByHandles :many
+SELECT id, handle
+FROM users
+WHERE handle = ANY($1::text[])
+`
+
+type GetUsersByHandlesRow struct {
+	ID     uuid.UUID
+	Handle sql.NullString
+}
+
+func (q *Queries) GetUsersByHandles(ctx context.Context, handles []string) ([]GetUsersByHandlesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersByHandles, pq.Array(handles))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUsersByHandlesRow
+	for rows.Next() {
+		var i GetUsersByHandlesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Handle,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsers = `-- name: ListUsers :many
+SELECT id, created_at, updated_at, email, hashed_password, chirpy_red, handle, role, suspended_at, password_reset_required, suspension_reason, suspended_until, email_verified_at, totp_secret, totp_pending_secret, totp_enabled_at, totp_last_step
+FROM users
+WHERE (
+    $1::text IS NULL
+    OR email ILIKE '%' || $1::text || '%'
+    OR handle ILIKE '%' || $1::text || '%'
+  )
+  AND (
+    $2::timestamp IS NULL
+    OR (created_at, id) < ($2::timestamp, $3::uuid)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type ListUsersParams struct {
+	Query           sql.NullString
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsers,
+		arg.Query,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.ChirpyRed,
+			&i.Handle,
+			&i.Role,
+			&i.SuspendedAt,
+			&i.PasswordResetRequired,
+			&i.SuspensionReason,
+			&i.SuspendedUntil,
+			&i.EmailVerifiedAt,
+			&i.TotpSecret,
+			&i.TotpPendingSecret,
+			&i.TotpEnabledAt,
+			&i.TotpLastStep,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const promoteUserByEmail = `-- name: PromoteUserByEmail :one
+UPDATE users
+SET role = $1,
+    updated_at = NOW()
+WHERE email = $2
+RETURNING id, created_at, updated_at, email, chirpy_red, handle, role
+`
+
+type PromoteUserByEmailParams struct {
+	Role  string
+	Email string
+}
+
+type PromoteUserByEmailRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Email     string
+	ChirpyRed sql.NullBool
+	Handle    sql.NullString
+	Role      string
+}
+
+func (q *Queries) PromoteUserByEmail(ctx context.Context, arg PromoteUserByEmailParams) (PromoteUserByEmailRow, error) {
+	row := q.db.QueryRowContext(ctx, promoteUserByEmail, arg.Role, arg.Email)
+	var i PromoteUserByEmailRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.ChirpyRed,
+		&i.Handle,
+		&i.Role,
 	)
 	return i, err
 }
 
+const requirePasswordReset = `-- name: RequirePasswordReset :execrows
+UPDATE users
+SET password_reset_required = TRUE,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) RequirePasswordReset(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, requirePasswordReset, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const suspendUser = `-- name: SuspendUser :execrows
+UPDATE users
+SET suspended_at = NOW(),
+    suspension_reason = $1,
+    suspended_until = CASE
+        WHEN $2::int IS NULL THEN NULL
+        ELSE NOW() + make_interval(secs => $2::int)
+    END,
+    updated_at = NOW()
+WHERE id = $3
+`
+
+type SuspendUserParams struct {
+	Reason          string
+	DurationSeconds sql.NullInt32
+	ID              uuid.UUID
+}
+
+func (q *Queries) SuspendUser(ctx context.Context, arg SuspendUserParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, suspendUser, arg.Reason, arg.DurationSeconds, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const unsuspendUser = `-- name: UnsuspendUser :execrows
+UPDATE users
+SET suspended_at = NULL,
+    suspension_reason = '',
+    suspended_until = NULL,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) UnsuspendUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, unsuspendUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE users
 SET email = $1,
     hashed_password = $2,
+    password_reset_required = password_reset_required AND NOT $3::boolean,
     updated_at = NOW()
-WHERE id = $3
-RETURNING id, created_at, updated_at, email, chirpy_red
+WHERE id = $4
+RETURNING id, created_at, updated_at, email, chirpy_red, handle
 `
 
 type UpdateUserParams struct {
-	Email          string
-	HashedPassword string
-	ID             uuid.UUID
+	Email           string
+	HashedPassword  string
+	PasswordChanged bool
+	ID              uuid.UUID
 }
 
 type UpdateUserRow struct {
@@ -136,10 +464,16 @@ type UpdateUserRow struct {
 	UpdatedAt time.Time
 	Email     string
 	ChirpyRed sql.NullBool
+	Handle    sql.NullString
 }
 
 func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (UpdateUserRow, error) {
-	row := q.db.QueryRowContext(ctx, updateUser, arg.Email, arg.HashedPassword, arg.ID)
+	row := q.db.QueryRowContext(ctx, updateUser,
+		arg.Email,
+		arg.HashedPassword,
+		arg.PasswordChanged,
+		arg.ID,
+	)
 	var i UpdateUserRow
 	err := row.Scan(
 		&i.ID,
@@ -147,6 +481,7 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (UpdateU
 		&i.UpdatedAt,
 		&i.Email,
 		&i.ChirpyRed,
+		&i.Handle,
 	)
 	return i, err
 }
@@ -184,3 +519,97 @@ func (q *Queries) UpdateUserChirpyRed(ctx context.Context, arg UpdateUserChirpyR
 	)
 	return i, err
 }
+
+const updateUserHandle = `-- name: UpdateUserHandle :exec
+UPDATE users
+SET handle = $1,
+    updated_at = NOW()
+WHERE id = $2
+`
+
+type UpdateUserHandleParams struct {
+	Handle sql.NullString
+	ID     uuid.UUID
+}
+
+func (q *Queries) UpdateUserHandle(ctx context.Context, arg UpdateUserHandleParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserHandle, arg.Handle, arg.ID)
+	return err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :one
+UPDATE users
+SET hashed_password = $1,
+    password_reset_required = FALSE,
+    updated_at = NOW()
+WHERE id = $2
+RETURNING id, created_at, updated_at, email, hashed_password, chirpy_red, handle, role, suspended_at, password_reset_required, suspension_reason, suspended_until, email_verified_at, totp_secret, totp_pending_secret, totp_enabled_at, totp_last_step
+`
+
+type UpdateUserPasswordParams struct {
+	HashedPassword string
+	ID             uuid.UUID
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserPassword, arg.HashedPassword, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.ChirpyRed,
+		&i.Handle,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.PasswordResetRequired,
+		&i.SuspensionReason,
+		&i.SuspendedUntil,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpPendingSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const updateUserRole = `-- name: UpdateUserRole :one
+UPDATE users
+SET role = $1,
+    updated_at = NOW()
+WHERE id = $2
+RETURNING id, created_at, updated_at, email, chirpy_red, handle, role
+`
+
+type UpdateUserRoleParams struct {
+	Role string
+	ID   uuid.UUID
+}
+
+type UpdateUserRoleRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Email     string
+	ChirpyRed sql.NullBool
+	Handle    sql.NullString
+	Role      string
+}
+
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (UpdateUserRoleRow, error) {
+	row := q.db.QueryRowContext(ctx, updateUserRole, arg.Role, arg.ID)
+	var i UpdateUserRoleRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.ChirpyRed,
+		&i.Handle,
+		&i.Role,
+	)
+	return i, err
+}
@@ -0,0 +1,151 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// maxAddressLength is the longest address SMTP can deliver to.
+const maxAddressLength = 254
+
+// ValidAddress reports whether s is a bare email address such as
+// "user@example.com", without a display name, angle brackets or
+// surrounding space, and with a dotted domain.
+func ValidAddress(s string) bool {
+	if len(s) > maxAddressLength {
+		return false
+	}
+	addr, err := netmail.ParseAddress(s)
+	if err != nil || addr.Name != "" || addr.Address != s {
+		return false
+	}
+	at := strings.LastIndexByte(s, '@')
+	domain := s[at+1:]
+	return strings.Contains(domain, ".") && !strings.HasPrefix(domain, ".") && !strings.HasSuffix(domain, ".")
+}
+
+// SenderAddress returns the bare address of from, which may carry a
+// display name as in "Chirpy <no-reply@example.com>". SMTP needs the bare
+// address as the envelope sender.
+func SenderAddress(from string) (string, error) {
+	addr, err := netmail.ParseAddress(from)
+	if err != nil {
+		return "", fmt.Errorf("invalid sender %q: %w", from, err)
+	}
+	return addr.Address, nil
+}
+
+// format renders msg as an RFC 5322 message.
+func format(from string, msg Message, date time.Time) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// validHeader reports whether s can be used in a header without injecting
+// other headers.
+func validHeader(s string) bool {
+	return !strings.ContainsAny(s, "\r\n")
+}
+
+func checkHeaders(from string, msg Message) error {
+	if !validHeader(from) || !validHeader(msg.To) || !validHeader(msg.Subject) {
+		return fmt.Errorf("mail headers must not contain line breaks")
+	}
+	return nil
+}
+
+// SMTPMailer sends mail through an SMTP server. Username and Password are
+// optional; when set, PLAIN authentication is used, which net/smtp only
+// allows over TLS or to localhost.
+type SMTPMailer struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m SMTPMailer) Send(_ context.Context, msg Message) error {
+	err := checkHeaders(m.From, msg)
+	if err != nil {
+		return err
+	}
+	sender, err := SenderAddress(m.From)
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return fmt.Errorf("invalid SMTP address: %w", err)
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	return smtp.SendMail(m.Addr, auth, sender, []string{msg.To}, format(m.From, msg, time.Now()))
+}
+
+// FileMailer writes each message to its own .eml file in Dir instead of
+// sending it. It is meant for local development and tests.
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+func (m FileMailer) Send(_ context.Context, msg Message) error {
+	err := checkHeaders(m.From, msg)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(m.Dir, 0o755)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405"), uuid.NewString())
+	return os.WriteFile(filepath.Join(m.Dir, name), format(m.From, msg, now), 0o600)
+}
+
+// LogMailer writes messages to the standard logger instead of sending
+// them. It is meant for local development.
+type LogMailer struct {
+	From string
+}
+
+func (m LogMailer) Send(_ context.Context, msg Message) error {
+	err := checkHeaders(m.From, msg)
+	if err != nil {
+		return err
+	}
+	log.Printf("mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
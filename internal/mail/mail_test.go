@@ -0,0 +1,195 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+	date := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	got := string(format("chirpy@example.com", Message{
+		To:      "user@example.com",
+		Subject: "Hello",
+		Body:    "line one\nline two",
+	}, date))
+	want := "From: chirpy@example.com\r\n" +
+		"To: user@example.com\r\n" +
+		"Subject: Hello\r\n" +
+		"Date: Tue, 02 Jan 2024 03:04:05 +0000\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" +
+		"line one\r\nline two"
+	if got != want {
+		t.Errorf("format() = %q, want %q", got, want)
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m := FileMailer{Dir: dir, From: "chirpy@example.com"}
+	err := m.Send(context.Background(), Message{To: "user@example.com", Subject: "Reset", Body: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || !strings.HasSuffix(entries[0].Name(), ".eml") {
+		t.Fatalf("expected one .eml file, got %v", entries)
+	}
+	dat, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(dat), "To: user@example.com\r\n") || !strings.HasSuffix(string(dat), "\r\ntoken") {
+		t.Errorf("unexpected message:\n%s", dat)
+	}
+}
+
+// fakeSMTPServer accepts one SMTP session on a local port and records the
+// commands and message it receives.
+func fakeSMTPServer(t *testing.T) (addr string, received <-chan []string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	ch := make(chan []string, 1)
+	go func() {
+		var lines []string
+		defer func() { ch <- lines }()
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 localhost ESMTP")
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			lines = append(lines, line)
+			switch {
+			case inData:
+				if line == "." {
+					inData = false
+					reply("250 OK")
+				}
+			case strings.HasPrefix(line, "EHLO"), strings.HasPrefix(line, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(line, "MAIL FROM:"), strings.HasPrefix(line, "RCPT TO:"):
+				reply("250 OK")
+			case line == "DATA":
+				inData = true
+				reply("354 End data with <CR><LF>.<CR><LF>")
+			case line == "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("502 Command not implemented")
+			}
+		}
+	}()
+	return ln.Addr().String(), ch
+}
+
+func TestSMTPMailer(t *testing.T) {
+	addr, received := fakeSMTPServer(t)
+	m := SMTPMailer{Addr: addr, From: "Chirpy <no-reply@example.com>"}
+	err := m.Send(context.Background(), Message{To: "user@example.com", Subject: "Reset", Body: "token"})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	lines := <-received
+	session := strings.Join(lines, "\n")
+	for _, want := range []string{
+		"MAIL FROM:<no-reply@example.com>",
+		"RCPT TO:<user@example.com>",
+		"From: Chirpy <no-reply@example.com>",
+		"To: user@example.com",
+	} {
+		found := false
+		for _, line := range lines {
+			if strings.HasPrefix(line, want) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("session has no line starting %q:\n%s", want, session)
+		}
+	}
+}
+
+func TestSenderAddress(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "no-reply@example.com", want: "no-reply@example.com"},
+		{in: "Chirpy <no-reply@localhost>", want: "no-reply@localhost"},
+		{in: "Chirpy", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tc := range tests {
+		got, err := SenderAddress(tc.in)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("SenderAddress(%q) = %q, %v, want %q, error %v", tc.in, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestHeaderInjection(t *testing.T) {
+	mailers := []Mailer{
+		SMTPMailer{Addr: "localhost:25", From: "chirpy@example.com"},
+		FileMailer{Dir: t.TempDir(), From: "chirpy@example.com"},
+		LogMailer{From: "chirpy@example.com"},
+	}
+	msg := Message{To: "user@example.com\r\nBcc: victim@example.com", Subject: "Hi"}
+	for _, m := range mailers {
+		if err := m.Send(context.Background(), msg); err == nil {
+			t.Errorf("%T accepted a recipient with a line break", m)
+		}
+	}
+}
+
+func TestValidAddress(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{in: "user@example.com", want: true},
+		{in: "first.last+tag@mail.example.co.uk", want: true},
+		{in: "", want: false},
+		{in: "user", want: false},
+		{in: "user@", want: false},
+		{in: "@example.com", want: false},
+		{in: "user@localhost", want: false},
+		{in: "user@example.", want: false},
+		{in: " user@example.com", want: false},
+		{in: "User <user@example.com>", want: false},
+		{in: "<user@example.com>", want: false},
+		{in: "user@example.com\r\nBcc: x@example.com", want: false},
+		{in: "a@b@example.com", want: false},
+		{in: strings.Repeat("a", 250) + "@example.com", want: false},
+	}
+	for _, tc := range tests {
+		if got := ValidAddress(tc.in); got != tc.want {
+			t.Errorf("ValidAddress(%q) = %v, want %v", tc.in, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package moderation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// DefaultMask replaces masked words when a rule has no replacement of its
+// own.
+const DefaultMask = "****"
+
+type MatchType string
+
+const (
+	// MatchWord only matches the pattern as a whole word.
+	MatchWord MatchType = "word"
+	// MatchSubstring matches the pattern anywhere, including inside words.
+	MatchSubstring MatchType = "substring"
+)
+
+type Action string
+
+const (
+	// ActionMask replaces the matched text.
+	ActionMask Action = "mask"
+	// ActionReject refuses the whole chirp.
+	ActionReject Action = "reject"
+	// ActionFlag keeps the text as is and marks the chirp for review.
+	ActionFlag Action = "flag"
+)
+
+// Rule is one entry of the moderation dictionary. Patterns are literal
+// text and always match case-insensitively.
+type Rule struct {
+	Pattern     string    `json:"pattern"`
+	MatchType   MatchType `json:"match_type"`
+	Action      Action    `json:"action"`
+	Replacement string    `json:"replacement,omitempty"`
+}
+
+func (r Rule) Validate() error {
+	if strings.TrimSpace(r.Pattern) == "" {
+		return fmt.Errorf("pattern must not be empty")
+	}
+	switch r.MatchType {
+	case MatchWord, MatchSubstring:
+	default:
+		return fmt.Errorf("unknown match type %q", r.MatchType)
+	}
+	switch r.Action {
+	case ActionMask, ActionReject, ActionFlag:
+	default:
+		return fmt.Errorf("unknown action %q", r.Action)
+	}
+	return nil
+}
+
+// Result is the outcome of running a text through a Matcher.
+type Result struct {
+	// Text is the input with every masked match replaced.
+	Text string
+	// Rejected is set when a reject rule matched.
+	Rejected bool
+	// Flagged holds the flag rules that matched, once each.
+	Flagged []Rule
+}
+
+// Matcher checks text against a fixed set of rules. Mask rules share a
+// single compiled regular expression. Reject and flag rules are each
+// matched on their own against the original text, so an overlapping mask
+// rule can never hide them.
+type Matcher struct {
+	re     *regexp.Regexp
+	rules  []Rule
+	checks []check
+}
+
+// check is a reject or flag rule with its own regular expression.
+type check struct {
+	re   *regexp.Regexp
+	rule Rule
+}
+
+// Compile builds a Matcher from rules. Mask rules are tried in order, so
+// when two mask patterns overlap at the same position the earlier rule
+// wins.
+func Compile(rules []Rule) (*Matcher, error) {
+	m := &Matcher{}
+	var alternatives []string
+	for i, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+		pattern := regexp.QuoteMeta(rule.Pattern)
+		if rule.MatchType == MatchWord {
+			pattern = `\b` + pattern + `\b`
+		}
+		if rule.Action != ActionMask {
+			re, err := regexp.Compile("(?i)" + pattern)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: %w", i, err)
+			}
+			m.checks = append(m.checks, check{re: re, rule: rule})
+			continue
+		}
+		alternatives = append(alternatives, "("+pattern+")")
+		m.rules = append(m.rules, rule)
+	}
+	if len(alternatives) > 0 {
+		re, err := regexp.Compile("(?i)" + strings.Join(alternatives, "|"))
+		if err != nil {
+			return nil, err
+		}
+		m.re = re
+	}
+	return m, nil
+}
+
+// Apply checks text against every reject and flag rule, then masks it in
+// a single pass.
+func (m *Matcher) Apply(text string) Result {
+	result := Result{Text: text}
+	for _, c := range m.checks {
+		if !c.re.MatchString(text) {
+			continue
+		}
+		switch c.rule.Action {
+		case ActionReject:
+			result.Rejected = true
+		case ActionFlag:
+			result.Flagged = append(result.Flagged, c.rule)
+		}
+	}
+	if m.re == nil {
+		return result
+	}
+	matches := m.re.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return result
+	}
+	var b strings.Builder
+	last := 0
+	for _, match := range matches {
+		rule := m.ruleFor(match)
+		b.WriteString(text[last:match[0]])
+		if rule.Replacement != "" {
+			b.WriteString(rule.Replacement)
+		} else {
+			b.WriteString(DefaultMask)
+		}
+		last = match[1]
+	}
+	b.WriteString(text[last:])
+	result.Text = b.String()
+	return result
+}
+
+// ruleFor returns the mask rule whose capture group produced match.
+func (m *Matcher) ruleFor(match []int) Rule {
+	for i := range m.rules {
+		if match[2*(i+1)] >= 0 {
+			return m.rules[i]
+		}
+	}
+	return Rule{}
+}
+
+// Filter holds the Matcher currently in use and lets it be replaced while
+// requests are being served.
+type Filter struct {
+	current atomic.Pointer[Matcher]
+}
+
+func NewFilter(m *Matcher) *Filter {
+	f := &Filter{}
+	f.Store(m)
+	return f
+}
+
+func (f *Filter) Store(m *Matcher) {
+	f.current.Store(m)
+}
+
+func (f *Filter) Apply(text string) Result {
+	return f.current.Load().Apply(text)
+}
+
+// LoadFile reads rules from a JSON file holding an array of rules. Missing
+// match types default to whole-word and missing actions to mask.
+func LoadFile(path string) ([]Rule, error) {
+	dat, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	err = json.Unmarshal(dat, &rules)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for i := range rules {
+		if rules[i].MatchType == "" {
+			rules[i].MatchType = MatchWord
+		}
+		if rules[i].Action == "" {
+			rules[i].Action = ActionMask
+		}
+	}
+	return rules, nil
+}
@@ -0,0 +1,196 @@
+package moderation
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatcherApply(t *testing.T) {
+	rules := []Rule{
+		{Pattern: "kerfuffle", MatchType: MatchWord, Action: ActionMask},
+		{Pattern: "sharbert", MatchType: MatchWord, Action: ActionMask, Replacement: "[removed]"},
+		{Pattern: "fornax", MatchType: MatchSubstring, Action: ActionMask},
+		{Pattern: "spamlink", MatchType: MatchSubstring, Action: ActionReject},
+		{Pattern: "suspicious", MatchType: MatchWord, Action: ActionFlag},
+	}
+	m, err := Compile(rules)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		text         string
+		wantText     string
+		wantRejected bool
+		wantFlagged  int
+	}{
+		{
+			name:     "clean text",
+			text:     "hello world",
+			wantText: "hello world",
+		},
+		{
+			name:     "whole word is masked case-insensitively",
+			text:     "What a Kerfuffle today",
+			wantText: "What a **** today",
+		},
+		{
+			name:     "whole word rule ignores substrings",
+			text:     "kerfuffled",
+			wantText: "kerfuffled",
+		},
+		{
+			name:     "custom replacement",
+			text:     "sharbert!",
+			wantText: "[removed]!",
+		},
+		{
+			name:     "substring rule matches inside words",
+			text:     "superfornaxes",
+			wantText: "super****es",
+		},
+		{
+			name:         "reject",
+			text:         "visit spamlink.example",
+			wantText:     "visit spamlink.example",
+			wantRejected: true,
+		},
+		{
+			name:        "flag keeps text",
+			text:        "suspicious and suspicious",
+			wantText:    "suspicious and suspicious",
+			wantFlagged: 1,
+		},
+		{
+			name:        "mask and flag together",
+			text:        "suspicious kerfuffle",
+			wantText:    "suspicious ****",
+			wantFlagged: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := m.Apply(tt.text)
+			if got.Text != tt.wantText {
+				t.Errorf("Apply().Text = %q, want %q", got.Text, tt.wantText)
+			}
+			if got.Rejected != tt.wantRejected {
+				t.Errorf("Apply().Rejected = %v, want %v", got.Rejected, tt.wantRejected)
+			}
+			if len(got.Flagged) != tt.wantFlagged {
+				t.Errorf("Apply().Flagged = %v, want %d rules", got.Flagged, tt.wantFlagged)
+			}
+		})
+	}
+}
+
+func TestOverlappingRules(t *testing.T) {
+	// File rules come before DB rules, so a broad mask rule can precede a
+	// stricter rule for the same text.
+	m, err := Compile([]Rule{
+		{Pattern: "spam", MatchType: MatchSubstring, Action: ActionMask},
+		{Pattern: "spamlink", MatchType: MatchSubstring, Action: ActionReject},
+		{Pattern: "spam", MatchType: MatchWord, Action: ActionFlag},
+		{Pattern: "spam link", MatchType: MatchSubstring, Action: ActionFlag},
+	})
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	got := m.Apply("visit spamlink.example")
+	if !got.Rejected {
+		t.Error("Apply().Rejected = false, want reject rule to fire under an overlapping mask")
+	}
+	if got.Text != "visit ****link.example" {
+		t.Errorf("Apply().Text = %q, want %q", got.Text, "visit ****link.example")
+	}
+
+	got = m.Apply("spam link")
+	if got.Rejected {
+		t.Error("Apply().Rejected = true, want false")
+	}
+	if len(got.Flagged) != 2 {
+		t.Errorf("Apply().Flagged = %v, want both overlapping flag rules", got.Flagged)
+	}
+	if got.Text != "**** link" {
+		t.Errorf("Apply().Text = %q, want %q", got.Text, "**** link")
+	}
+}
+
+func TestCompileInvalidRule(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{name: "empty pattern", rule: Rule{Pattern: " ", MatchType: MatchWord, Action: ActionMask}},
+		{name: "unknown match type", rule: Rule{Pattern: "x", MatchType: "regex", Action: ActionMask}},
+		{name: "unknown action", rule: Rule{Pattern: "x", MatchType: MatchWord, Action: "ban"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Compile([]Rule{tt.rule}); err == nil {
+				t.Error("Compile() expected an error")
+			}
+		})
+	}
+}
+
+func TestCompileQuotesPatterns(t *testing.T) {
+	m, err := Compile([]Rule{{Pattern: "a.b", MatchType: MatchSubstring, Action: ActionMask}})
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if got := m.Apply("axb a.b").Text; got != "axb ****" {
+		t.Errorf("Apply().Text = %q, want %q", got, "axb ****")
+	}
+}
+
+func TestEmptyMatcher(t *testing.T) {
+	m, err := Compile(nil)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if got := m.Apply("anything").Text; got != "anything" {
+		t.Errorf("Apply().Text = %q, want %q", got, "anything")
+	}
+}
+
+func TestFilterStore(t *testing.T) {
+	first, _ := Compile([]Rule{{Pattern: "one", MatchType: MatchWord, Action: ActionMask}})
+	second, _ := Compile([]Rule{{Pattern: "two", MatchType: MatchWord, Action: ActionMask}})
+	f := NewFilter(first)
+	if got := f.Apply("one two").Text; got != "**** two" {
+		t.Errorf("Apply().Text = %q, want %q", got, "**** two")
+	}
+	f.Store(second)
+	if got := f.Apply("one two").Text; got != "one ****" {
+		t.Errorf("Apply().Text after Store = %q, want %q", got, "one ****")
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	err := os.WriteFile(path, []byte(`[
+		{"pattern": "kerfuffle"},
+		{"pattern": "spam", "match_type": "substring", "action": "reject"}
+	]`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("LoadFile() returned %d rules, want 2", len(rules))
+	}
+	if rules[0].MatchType != MatchWord || rules[0].Action != ActionMask {
+		t.Errorf("LoadFile() defaults = %v, want word/mask", rules[0])
+	}
+	if rules[1].MatchType != MatchSubstring || rules[1].Action != ActionReject {
+		t.Errorf("LoadFile() rule = %v, want substring/reject", rules[1])
+	}
+}
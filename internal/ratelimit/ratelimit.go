@@ -0,0 +1,132 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/UUest/gohttp/internal/cache"
+)
+
+// Limit describes a token bucket. The bucket holds at most Burst tokens
+// and gains one every Every. Each request takes one token.
+type Limit struct {
+	Burst int
+	Every time.Duration
+}
+
+// Per returns the limit that allows n requests per period, all of which
+// may be made at once. Every is rounded down to the nanosecond, so it is
+// zero when n is larger than period in nanoseconds.
+func Per(n int, period time.Duration) Limit {
+	return Limit{Burst: n, Every: period / time.Duration(n)}
+}
+
+// ParseLimit parses limits written as "<requests>/<period>", e.g. "5/1m"
+// or "100/1h". A bare unit such as "10/s" means one of that unit.
+func ParseLimit(s string) (Limit, error) {
+	count, period, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid limit %q: want <requests>/<period>", s)
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil || n < 1 {
+		return Limit{}, fmt.Errorf("invalid request count %q", count)
+	}
+	if period != "" && (period[0] < '0' || period[0] > '9') {
+		period = "1" + period
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("invalid period %q", period)
+	}
+	limit := Per(n, d)
+	if limit.Every <= 0 {
+		return Limit{}, fmt.Errorf("invalid limit %q: more than one request per nanosecond", s)
+	}
+	return limit, nil
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Burst, l.Every*time.Duration(l.Burst))
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed bool
+	// Limit is the bucket size.
+	Limit int
+	// Remaining is the number of whole tokens left after this request.
+	Remaining int
+	// RetryAfter is how long a rejected caller must wait for a token. It is
+	// zero when the request was allowed.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// Store keeps token buckets. Implementations shared between replicas,
+// such as one backed by Postgres, make the limits apply across all of
+// them.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// MemoryStore is a Store that keeps buckets in process memory. Limits are
+// per replica.
+type MemoryStore struct {
+	now func() time.Time
+
+	mu sync.Mutex
+	// buckets holds one cache per limit. A bucket expires once it has had
+	// time to refill, since a missing bucket is treated as a full one.
+	buckets map[Limit]*cache.Cache[string, bucket]
+}
+
+func NewMemoryStore() *MemoryStore {
+	return newMemoryStoreWithClock(time.Now)
+}
+
+func newMemoryStoreWithClock(now func() time.Time) *MemoryStore {
+	return &MemoryStore{
+		now:     now,
+		buckets: make(map[Limit]*cache.Cache[string, bucket]),
+	}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	buckets, ok := s.buckets[limit]
+	if !ok {
+		buckets = cache.NewWithClock[string, bucket](limit.Every*time.Duration(limit.Burst), s.now)
+		s.buckets[limit] = buckets
+	}
+
+	b, ok := buckets.Get(key)
+	if !ok {
+		b = bucket{tokens: float64(limit.Burst), updated: now}
+	}
+	b.tokens = min(float64(limit.Burst), b.tokens+float64(now.Sub(b.updated))/float64(limit.Every))
+	b.updated = now
+	res := Result{Limit: limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration((1 - b.tokens) * float64(limit.Every))
+	}
+	buckets.Set(key, b)
+	res.Remaining = int(b.tokens)
+	res.Reset = time.Duration((float64(limit.Burst) - b.tokens) * float64(limit.Every))
+	return res, nil
+}
@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Limit
+		wantErr bool
+	}{
+		{in: "5/1m", want: Limit{Burst: 5, Every: 12 * time.Second}},
+		{in: "10/s", want: Limit{Burst: 10, Every: 100 * time.Millisecond}},
+		{in: "100/1h", want: Limit{Burst: 100, Every: 36 * time.Second}},
+		{in: "5", wantErr: true},
+		{in: "0/1m", wantErr: true},
+		{in: "5/forever", wantErr: true},
+		{in: "5/-1m", wantErr: true},
+		{in: "2/1ns", wantErr: true},
+		{in: "1000/1us", want: Limit{Burst: 1000, Every: time.Nanosecond}},
+	}
+	for _, tc := range tests {
+		got, err := ParseLimit(tc.in)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ParseLimit(%q) error = %v, wantErr %v", tc.in, err, tc.wantErr)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("ParseLimit(%q) = %+v, want %+v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := newMemoryStoreWithClock(func() time.Time { return now })
+	limit := Per(3, 3*time.Second)
+	ctx := context.Background()
+
+	for i := 2; i >= 0; i-- {
+		res, _ := store.Take(ctx, "a", limit)
+		if !res.Allowed || res.Remaining != i {
+			t.Fatalf("Take = %+v, want allowed with %d remaining", res, i)
+		}
+	}
+	res, _ := store.Take(ctx, "a", limit)
+	if res.Allowed {
+		t.Fatal("Take allowed a request from an empty bucket")
+	}
+	if res.RetryAfter != time.Second {
+		t.Errorf("RetryAfter = %s, want 1s", res.RetryAfter)
+	}
+	if res.Reset != 3*time.Second {
+		t.Errorf("Reset = %s, want 3s", res.Reset)
+	}
+
+	res, _ = store.Take(ctx, "b", limit)
+	if !res.Allowed {
+		t.Error("Take rejected a different key")
+	}
+
+	now = now.Add(time.Second)
+	res, _ = store.Take(ctx, "a", limit)
+	if !res.Allowed || res.Remaining != 0 {
+		t.Errorf("Take after refill = %+v, want allowed with 0 remaining", res)
+	}
+
+	now = now.Add(time.Hour)
+	res, _ = store.Take(ctx, "a", limit)
+	if !res.Allowed || res.Remaining != 2 {
+		t.Errorf("Take after idle = %+v, want allowed with 2 remaining", res)
+	}
+}
+
+func TestMemoryStoreForgetsRefilledBuckets(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := newMemoryStoreWithClock(func() time.Time { return now })
+	limit := Per(3, 3*time.Second)
+	ctx := context.Background()
+
+	store.Take(ctx, "a", limit)
+	if _, ok := store.buckets[limit].Get("a"); !ok {
+		t.Fatal("bucket was not stored")
+	}
+
+	now = now.Add(3 * time.Second)
+	if _, ok := store.buckets[limit].Get("a"); ok {
+		t.Error("bucket was kept after it had time to refill")
+	}
+}
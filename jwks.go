@@ -0,0 +1,20 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// getJWKS publishes the public keys that verify access tokens so other
+// services can validate them. HS256 secrets are never included.
+func (cfg *apiConfig) getJWKS(w http.ResponseWriter, r *http.Request) {
+	dat, err := json.Marshal(cfg.jwtKeys.JWKS())
+	if err != nil {
+		log.Printf("failed to marshal JWKS: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, http.StatusOK, dat)
+}
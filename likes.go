@@ -0,0 +1,68 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/UUest/gohttp/internal/database"
+)
+
+func (cfg *apiConfig) likeChirp(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, nil)
+		return
+	}
+	_, err = cfg.dbQueries.GetVisibleChirpByID(r.Context(), chirpUUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, nil)
+		return
+	}
+	if err != nil {
+		log.Printf("failed to get chirp by id: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	err = cfg.dbQueries.LikeChirp(r.Context(), database.LikeChirpParams{
+		UserID:  userID,
+		ChirpID: chirpUUID,
+	})
+	if err != nil {
+		log.Printf("failed to like chirp: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (cfg *apiConfig) unlikeChirp(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, nil)
+		return
+	}
+	err = cfg.dbQueries.UnlikeChirp(r.Context(), database.UnlikeChirpParams{
+		UserID:  userID,
+		ChirpID: chirpUUID,
+	})
+	if err != nil {
+		log.Printf("failed to unlike chirp: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
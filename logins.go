@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/UUest/gohttp/internal/auth"
+	"github.com/UUest/gohttp/internal/database"
+)
+
+const (
+	// loginFailureWindow is how long a failed login counts against its
+	// account and address.
+	loginFailureWindow   = time.Hour
+	loginBackoffBase     = time.Second
+	loginBackoffMax      = 5 * time.Minute
+	loginLockoutDuration = 15 * time.Minute
+)
+
+// loginThrottle describes how failed logins against one kind of subject
+// are slowed down. After freeAttempts failures each further failure
+// doubles the wait before the next attempt, and lockoutAfter failures lock
+// the subject out for loginLockoutDuration.
+type loginThrottle struct {
+	kind         string
+	freeAttempts int32
+	lockoutAfter int32
+}
+
+var (
+	accountLoginThrottle = loginThrottle{kind: "account", freeAttempts: 3, lockoutAfter: 10}
+	ipLoginThrottle      = loginThrottle{kind: "ip", freeAttempts: 20, lockoutAfter: 100}
+)
+
+func (t loginThrottle) backoff(failures int32) time.Duration {
+	if failures <= t.freeAttempts {
+		return 0
+	}
+	n := failures - t.freeAttempts - 1
+	if n >= 16 {
+		return loginBackoffMax
+	}
+	return min(loginBackoffBase<<n, loginBackoffMax)
+}
+
+func accountLoginSubject(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipLoginSubject(ip string) string {
+	return "ip:" + ip
+}
+
+// dummyPasswordHash is checked against when a login names an unknown
+// email, so that it takes as long as a wrong password.
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, err := auth.HashPassword(uuid.NewString())
+	if err != nil {
+		log.Fatalf("failed to hash dummy password: %s", err)
+	}
+	return hash
+})
+
+// checkLoginThrottle rejects the login with 429 Too Many Requests while
+// the account or the client address is backing off or locked out.
+func (cfg *apiConfig) checkLoginThrottle(w http.ResponseWriter, r *http.Request, email string) bool {
+	retryAfter, err := cfg.dbQueries.GetLoginRetryAfter(r.Context(), []string{
+		accountLoginSubject(email),
+		ipLoginSubject(cfg.clientIP(r)),
+	})
+	if err != nil {
+		log.Printf("failed to get login retry after: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return false
+	}
+	if retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter)))
+		respondWithError(w, http.StatusTooManyRequests, []byte("too many failed login attempts, try again later"))
+		return false
+	}
+	return true
+}
+
+// failLogin records a failed login against the account and the client
+// address and responds with the same error whether or not the account
+// exists.
+func (cfg *apiConfig) failLogin(w http.ResponseWriter, r *http.Request, email string, userID uuid.NullUUID) {
+	ip := cfg.clientIP(r)
+	cfg.recordLoginFailure(r.Context(), accountLoginThrottle, accountLoginSubject(email), email, ip, userID)
+	cfg.recordLoginFailure(r.Context(), ipLoginThrottle, ipLoginSubject(ip), email, ip, userID)
+	respondWithError(w, http.StatusUnauthorized, []byte("Incorrect email or password"))
+}
+
+func (cfg *apiConfig) recordLoginFailure(ctx context.Context, throttle loginThrottle, subject, email, ip string, userID uuid.NullUUID) {
+	failures, err := cfg.dbQueries.RecordLoginFailure(ctx, database.RecordLoginFailureParams{
+		Subject:       subject,
+		WindowSeconds: int32(loginFailureWindow.Seconds()),
+	})
+	if err != nil {
+		log.Printf("failed to record login failure: %s", err)
+		return
+	}
+	wait := throttle.backoff(failures)
+	locked := failures >= throttle.lockoutAfter
+	if locked {
+		wait = loginLockoutDuration
+	}
+	if wait == 0 {
+		return
+	}
+	err = cfg.dbQueries.BlockLogin(ctx, database.BlockLoginParams{
+		Seconds:       int32(wait.Seconds()),
+		ResetFailures: locked,
+		Subject:       subject,
+	})
+	if err != nil {
+		log.Printf("failed to block login: %s", err)
+		return
+	}
+	if !locked {
+		return
+	}
+	_, err = cfg.dbQueries.CreateLoginLockout(ctx, database.CreateLoginLockoutParams{
+		Kind:     throttle.kind,
+		Email:    email,
+		UserID:   userID,
+		Ip:       ip,
+		Failures: failures,
+		Seconds:  int32(wait.Seconds()),
+	})
+	if err != nil {
+		log.Printf("failed to record login lockout: %s", err)
+	}
+}
+
+// listLoginLockouts lists lockouts newest first. ?user_id= narrows the
+// list to lockouts of one account.
+func (cfg *apiConfig) listLoginLockouts(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePageRequest(r)
+	if err != nil {
+		log.Printf("failed to parse page request: %s", err)
+		respondWithError(w, http.StatusBadRequest, []byte("invalid limit or cursor"))
+		return
+	}
+	userID := uuid.NullUUID{}
+	if raw := r.URL.Query().Get("user_id"); raw != "" {
+		parsed, err := uuid.Parse(raw)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, []byte("invalid user_id"))
+			return
+		}
+		userID = uuid.NullUUID{UUID: parsed, Valid: true}
+	}
+	lockouts, err := cfg.dbQueries.ListLoginLockouts(r.Context(), database.ListLoginLockoutsParams{
+		UserID:          userID,
+		CursorCreatedAt: page.CursorCreatedAt,
+		CursorID:        page.CursorID,
+		PageSize:        int32(page.Limit + 1),
+	})
+	if err != nil {
+		log.Printf("failed to list login lockouts: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	lockouts, hasMore := trimPage(lockouts, page.Limit)
+	type lockoutParameters struct {
+		Id           uuid.UUID  `json:"id"`
+		Created_at   time.Time  `json:"created_at"`
+		Kind         string     `json:"kind"`
+		Email        string     `json:"email"`
+		User_id      *uuid.UUID `json:"user_id,omitempty"`
+		Ip           string     `json:"ip"`
+		Failures     int32      `json:"failures"`
+		Locked_until time.Time  `json:"locked_until"`
+		Active       bool       `json:"active"`
+	}
+	resLockouts := make([]lockoutParameters, 0, len(lockouts))
+	for _, lockout := range lockouts {
+		resLockouts = append(resLockouts, lockoutParameters{
+			Id:           lockout.ID,
+			Created_at:   lockout.CreatedAt,
+			Kind:         lockout.Kind,
+			Email:        lockout.Email,
+			User_id:      nullUUIDPtr(lockout.UserID),
+			Ip:           lockout.Ip,
+			Failures:     lockout.Failures,
+			Locked_until: lockout.LockedUntil,
+			Active:       lockout.Active,
+		})
+	}
+	nextCursor := ""
+	if hasMore && len(lockouts) > 0 {
+		last := lockouts[len(lockouts)-1]
+		nextCursor = encodeCursor(pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+		setNextLink(w, r, nextCursor, page.Limit)
+	}
+	type resParameters struct {
+		Lockouts   []lockoutParameters `json:"lockouts"`
+		NextCursor string              `json:"next_cursor,omitempty"`
+	}
+	dat, err := json.Marshal(resParameters{
+		Lockouts:   resLockouts,
+		NextCursor: nextCursor,
+	})
+	if err != nil {
+		log.Printf("failed to marshal response body: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, dat)
+}
+
+// clearUserLockout lifts any backoff or lockout on an account's logins.
+func (cfg *apiConfig) clearUserLockout(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.dbQueries.ClearLoginFailures(r.Context(), accountLoginSubject(user.Email))
+	if err != nil {
+		log.Printf("failed to clear login failures: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
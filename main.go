@@ -6,17 +6,26 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/UUest/gohttp/internal/auth"
+	"github.com/UUest/gohttp/internal/cache"
 	"github.com/UUest/gohttp/internal/database"
+	"github.com/UUest/gohttp/internal/mail"
+	"github.com/UUest/gohttp/internal/ratelimit"
+	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
 func main() {
 	godotenv.Load()
-	jwtSecret := os.Getenv("JWT_SECRET")
 	platform := os.Getenv("PLATFORM")
 	polkaKey := os.Getenv("POLKA_KEY")
+	moderationFile := os.Getenv("MODERATION_RULES_FILE")
+	trustProxy := os.Getenv("TRUST_PROXY") == "true"
+	requireEmailVerification := os.Getenv("REQUIRE_EMAIL_VERIFICATION") == "true"
 	dbUrl := os.Getenv("DB_URL")
 	db, err := sql.Open("postgres", dbUrl)
 	if err != nil {
@@ -29,25 +38,190 @@ func main() {
 		Handler: mux,
 	}
 	cfg := &apiConfig{
-		dbQueries: database.New(db),
-		platform:  platform,
-		jwtSecret: jwtSecret,
-		polkaKey:  polkaKey,
+		db:                       db,
+		dbQueries:                database.New(db),
+		platform:                 platform,
+		jwtKeys:                  newJWTKeys(),
+		polkaKey:                 polkaKey,
+		moderation:               newModerationSource(moderationFile),
+		accountCache:             cache.New[uuid.UUID, database.GetUserAccountStatusRow](accountStatusTTL),
+		rateLimiter:              ratelimit.NewMemoryStore(),
+		trustProxy:               trustProxy,
+		mailer:                   newMailer(),
+		requireEmailVerification: requireEmailVerification,
 	}
+	err = cfg.moderation.reload(context.Background(), cfg.dbQueries)
+	if err != nil {
+		log.Fatalf("failed to load moderation rules: %s", err)
+	}
+	go cfg.moderation.watch(context.Background(), cfg.dbQueries)
 	mux.HandleFunc("GET /api/healthz", readiness)
+	mux.HandleFunc("GET /.well-known/jwks.json", cfg.getJWKS)
 	mux.Handle("/app/", cfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(".")))))
-	mux.HandleFunc("GET /admin/metrics", cfg.writeMetricsResponse)
-	mux.HandleFunc("POST /admin/reset", cfg.deleteAllUsers)
-	mux.HandleFunc("POST /api/users", cfg.createUser)
-	mux.HandleFunc("POST /api/chirps", cfg.createChirp)
+	mux.HandleFunc("GET /admin/metrics", cfg.requireRole(cfg.writeMetricsResponse, auth.RoleAdmin))
+	mux.HandleFunc("POST /admin/reset", cfg.requireRole(cfg.deleteAllUsers, auth.RoleAdmin))
+	mux.HandleFunc("GET /admin/moderation/rules", cfg.requireRole(cfg.listModerationRules, auth.RoleAdmin))
+	mux.HandleFunc("POST /admin/moderation/rules", cfg.requireRole(cfg.createModerationRule, auth.RoleAdmin))
+	mux.HandleFunc("PUT /admin/moderation/rules/{ruleID}", cfg.requireRole(cfg.updateModerationRule, auth.RoleAdmin))
+	mux.HandleFunc("DELETE /admin/moderation/rules/{ruleID}", cfg.requireRole(cfg.deleteModerationRule, auth.RoleAdmin))
+	mux.HandleFunc("GET /admin/moderation/queue", cfg.requireRole(cfg.getModerationQueue, auth.RoleModerator))
+	mux.HandleFunc("GET /admin/moderation/decisions", cfg.requireRole(cfg.getModerationDecisions, auth.RoleModerator))
+	mux.HandleFunc("GET /admin/moderation/chirps/{chirpID}/reports", cfg.requireRole(cfg.getChirpReports, auth.RoleModerator))
+	mux.HandleFunc("POST /admin/moderation/chirps/{chirpID}/decision", cfg.requireRole(cfg.decideChirp, auth.RoleModerator))
+	mux.HandleFunc("GET /admin/users", cfg.requireRole(cfg.listUsers, auth.RoleAdmin))
+	mux.HandleFunc("GET /admin/users/{userID}", cfg.requireRole(cfg.getUser, auth.RoleAdmin))
+	mux.HandleFunc("GET /admin/users/{userID}/chirps", cfg.requireRole(cfg.getUserChirps, auth.RoleAdmin))
+	mux.HandleFunc("GET /admin/users/{userID}/sessions", cfg.requireRole(cfg.getUserSessions, auth.RoleAdmin))
+	mux.HandleFunc("POST /admin/users/{userID}/suspend", cfg.requireRole(cfg.suspendUser, auth.RoleAdmin))
+	mux.HandleFunc("DELETE /admin/users/{userID}/suspend", cfg.requireRole(cfg.unsuspendUser, auth.RoleAdmin))
+	mux.HandleFunc("POST /admin/users/{userID}/password-reset", cfg.requireRole(cfg.forcePasswordReset, auth.RoleAdmin))
+	mux.HandleFunc("PUT /admin/users/{userID}/chirpy-red", cfg.requireRole(cfg.setUserChirpyRed, auth.RoleAdmin))
+	mux.HandleFunc("PUT /admin/users/{userID}/role", cfg.requireRole(cfg.setUserRole, auth.RoleAdmin))
+	mux.HandleFunc("DELETE /admin/users/{userID}/lockout", cfg.requireRole(cfg.clearUserLockout, auth.RoleAdmin))
+	mux.HandleFunc("GET /admin/lockouts", cfg.requireRole(cfg.listLoginLockouts, auth.RoleAdmin))
+	mux.HandleFunc("POST /api/users", cfg.rateLimit(cfg.createUser, routeLimit("signup", "5/1h", rateLimitByIP)))
+	mux.HandleFunc("POST /api/chirps", cfg.rateLimit(cfg.createChirp, routeLimit("chirps", "30/1m", rateLimitByUser)))
 	mux.HandleFunc("GET /api/chirps", cfg.getChirps)
+	mux.HandleFunc("GET /api/chirps/search", cfg.searchChirps)
 	mux.HandleFunc("GET /api/chirps/{chirpID}", cfg.getChirpByID)
-	mux.HandleFunc("POST /api/login", cfg.loginUser)
-	mux.HandleFunc("POST /api/refresh", cfg.RefreshToken)
+	mux.HandleFunc("POST /api/login", cfg.rateLimit(cfg.loginUser, routeLimit("login", "10/1m", rateLimitByIP)))
+	mux.HandleFunc("POST /api/login/2fa", cfg.rateLimit(cfg.loginSecondFactor, routeLimit("login_2fa", "10/1m", rateLimitByIP)))
+	mux.HandleFunc("GET /api/2fa", cfg.getTwoFactorStatus)
+	mux.HandleFunc("POST /api/2fa/totp/enroll", cfg.enrollTOTP)
+	mux.HandleFunc("POST /api/2fa/totp/confirm", cfg.rateLimit(cfg.confirmTOTP, routeLimit("totp_confirm", "10/1m", rateLimitByUser)))
+	mux.HandleFunc("DELETE /api/2fa/totp", cfg.rateLimit(cfg.disableTOTP, routeLimit("totp_disable", "10/1m", rateLimitByUser)))
+	mux.HandleFunc("POST /api/refresh", cfg.rateLimit(cfg.RefreshToken, routeLimit("refresh", "30/1m", rateLimitByIP)))
 	mux.HandleFunc("POST /api/revoke", cfg.RevokeToken)
+	mux.HandleFunc("POST /api/logout-all", cfg.logoutAll)
+	mux.HandleFunc("GET /api/sessions", cfg.getSessions)
+	mux.HandleFunc("DELETE /api/sessions/{sessionID}", cfg.deleteSession)
+	mux.HandleFunc("POST /api/password/forgot", cfg.rateLimit(cfg.forgotPassword, routeLimit("password_forgot", "5/1h", rateLimitByIP)))
+	mux.HandleFunc("POST /api/email/verify", cfg.rateLimit(cfg.verifyEmail, routeLimit("email_verify", "20/1h", rateLimitByIP)))
+	mux.HandleFunc("POST /api/email/verify/resend", cfg.rateLimit(cfg.resendEmailVerification, routeLimit("email_resend", "5/1h", rateLimitByUser)))
+	mux.HandleFunc("POST /api/password/reset", cfg.rateLimit(cfg.resetPassword, routeLimit("password_reset", "10/1h", rateLimitByIP)))
 	mux.HandleFunc("PUT /api/users", cfg.updateUser)
 	mux.HandleFunc("DELETE /api/chirps/{chirpID}", cfg.deleteChirpByID)
+	mux.HandleFunc("PUT /api/chirps/{chirpID}", cfg.updateChirp)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/revisions", cfg.getChirpRevisions)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/thread", cfg.getChirpThread)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/like", cfg.likeChirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/like", cfg.unlikeChirp)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/rechirp", cfg.rechirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/rechirp", cfg.undoRechirp)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/report", cfg.rateLimit(cfg.reportChirp, routeLimit("reports", "20/1h", rateLimitByUser)))
 	mux.HandleFunc("POST /api/polka/webhooks", cfg.updateUserChirpyRed)
+	mux.HandleFunc("POST /api/users/{userID}/follow", cfg.followUser)
+	mux.HandleFunc("DELETE /api/users/{userID}/follow", cfg.unfollowUser)
+	mux.HandleFunc("GET /api/users/{userID}/followers", cfg.getFollowers)
+	mux.HandleFunc("GET /api/users/{userID}/following", cfg.getFollowing)
+	mux.HandleFunc("POST /api/users/{userID}/block", cfg.blockUser)
+	mux.HandleFunc("DELETE /api/users/{userID}/block", cfg.unblockUser)
+	mux.HandleFunc("POST /api/users/{userID}/mute", cfg.muteUser)
+	mux.HandleFunc("DELETE /api/users/{userID}/mute", cfg.unmuteUser)
+	mux.HandleFunc("GET /api/users/me/mentions", cfg.getMyMentions)
+	mux.HandleFunc("GET /api/timeline", cfg.getTimeline)
+	mux.HandleFunc("GET /api/hashtags/trending", cfg.getTrendingHashtags)
+	mux.HandleFunc("GET /api/hashtags/{tag}/chirps", cfg.getHashtagChirps)
 	server.ListenAndServe()
 	defer server.Shutdown(context.Background())
 }
+
+// newMailer builds the mailer selected by MAIL_DRIVER: "smtp", "file" or
+// "log", the default.
+func newMailer() mail.Mailer {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "Chirpy <no-reply@localhost>"
+	}
+	if _, err := mail.SenderAddress(from); err != nil {
+		log.Fatalf("invalid MAIL_FROM: %s", err)
+	}
+	switch driver := os.Getenv("MAIL_DRIVER"); driver {
+	case "smtp":
+		return mail.SMTPMailer{
+			Addr:     os.Getenv("SMTP_ADDR"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}
+	case "file":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "mail"
+		}
+		return mail.FileMailer{Dir: dir, From: from}
+	case "", "log":
+		return mail.LogMailer{From: from}
+	default:
+		log.Fatalf("unknown MAIL_DRIVER %q", driver)
+		return nil
+	}
+}
+
+// newJWTKeys builds the access token key set. Tokens are signed with the
+// RSA or Ed25519 key in JWT_SIGNING_KEY_FILE, or with JWT_SECRET using
+// HS256 when no key file is set. JWT_VERIFICATION_KEY_FILES lists more PEM
+// files, comma separated, whose tokens are still accepted after a
+// rotation. JWT_SECRET keeps verifying old HS256 tokens until it is unset.
+// Tokens must carry the JWT_AUDIENCE audience, be signed with one of
+// JWT_ALGORITHMS and are allowed JWT_LEEWAY of clock skew.
+func newJWTKeys() *auth.KeySet {
+	config := auth.ValidatorConfig{
+		Audience: os.Getenv("JWT_AUDIENCE"),
+		Leeway:   30 * time.Second,
+	}
+	if config.Audience == "" {
+		config.Audience = "chirpy-api"
+	}
+	for _, alg := range strings.Split(os.Getenv("JWT_ALGORITHMS"), ",") {
+		if alg = strings.TrimSpace(alg); alg != "" {
+			config.Algorithms = append(config.Algorithms, alg)
+		}
+	}
+	if leeway := os.Getenv("JWT_LEEWAY"); leeway != "" {
+		d, err := time.ParseDuration(leeway)
+		if err != nil || d < 0 {
+			log.Fatalf("invalid JWT_LEEWAY %q", leeway)
+		}
+		config.Leeway = d
+	}
+	if until := os.Getenv("JWT_AUDIENCE_OPTIONAL_UNTIL"); until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			log.Fatalf("invalid JWT_AUDIENCE_OPTIONAL_UNTIL %q", until)
+		}
+		config.AllowMissingAudienceUntil = t
+	}
+	secret := os.Getenv("JWT_SECRET")
+	signing := auth.NewHMACKey("", []byte(secret))
+	var verifying []*auth.Key
+	if path := os.Getenv("JWT_SIGNING_KEY_FILE"); path != "" {
+		key, err := auth.LoadKeyFile(path)
+		if err != nil {
+			log.Fatalf("failed to load JWT signing key: %s", err)
+		}
+		if !key.CanSign() {
+			log.Fatalf("JWT_SIGNING_KEY_FILE %s holds a public key", path)
+		}
+		if secret != "" {
+			verifying = append(verifying, signing)
+		}
+		signing = key
+	}
+	for _, path := range strings.Split(os.Getenv("JWT_VERIFICATION_KEY_FILES"), ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		key, err := auth.LoadKeyFile(path)
+		if err != nil {
+			log.Fatalf("failed to load JWT verification key: %s", err)
+		}
+		verifying = append(verifying, key)
+	}
+	keys, err := auth.NewKeySet(config, signing, verifying...)
+	if err != nil {
+		log.Fatalf("failed to build JWT key set: %s", err)
+	}
+	return keys
+}
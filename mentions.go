@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/UUest/gohttp/internal/chirptext"
+	"github.com/UUest/gohttp/internal/database"
+)
+
+// mentionResponse locates an @handle inside a chirp body. Start and End
+// are rune offsets, End exclusive, and include the leading '@'.
+type mentionResponse struct {
+	User_id uuid.UUID `json:"user_id"`
+	Handle  string    `json:"handle"`
+	Start   int32     `json:"start"`
+	End     int32     `json:"end"`
+}
+
+// saveChirpMentions replaces the mentions stored for chirp with the
+// @handles in its current body that belong to existing users. Users on
+// either side of a block with the author are not linked.
+func saveChirpMentions(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	err := q.DeleteChirpMentions(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+	mentions := chirptext.Mentions(chirp.Body)
+	var handles []string
+	for _, mention := range mentions {
+		if chirptext.ValidHandle(mention.Handle) {
+			handles = append(handles, mention.Handle)
+		}
+	}
+	if len(handles) == 0 {
+		return nil
+	}
+	users, err := q.GetUsersByHandles(ctx, handles)
+	if err != nil {
+		return err
+	}
+	blockedIDs, err := q.GetBlockedUserIDs(ctx, chirp.UserID)
+	if err != nil {
+		return err
+	}
+	blocked := make(map[uuid.UUID]bool, len(blockedIDs))
+	for _, id := range blockedIDs {
+		blocked[id] = true
+	}
+	userIDs := make(map[string]uuid.UUID, len(users))
+	for _, user := range users {
+		if blocked[user.ID] {
+			continue
+		}
+		userIDs[user.Handle.String] = user.ID
+	}
+	for _, mention := range mentions {
+		userID, ok := userIDs[mention.Handle]
+		if !ok {
+			continue
+		}
+		err = q.AddChirpMention(ctx, database.AddChirpMentionParams{
+			ChirpID:    chirp.ID,
+			UserID:     userID,
+			Handle:     mention.Handle,
+			StartIndex: int32(mention.Start),
+			EndIndex:   int32(mention.End),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getMyMentions lists the chirps that mention the caller, newest first.
+func (cfg *apiConfig) getMyMentions(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	page, err := parsePageRequest(r)
+	if err != nil {
+		log.Printf("failed to parse page request: %s", err)
+		respondWithError(w, http.StatusBadRequest, []byte("invalid limit or cursor"))
+		return
+	}
+	chirps, err := cfg.dbQueries.ListMentionedChirps(r.Context(), database.ListMentionedChirpsParams{
+		UserID:          userID,
+		CursorCreatedAt: page.CursorCreatedAt,
+		CursorID:        page.CursorID,
+		PageSize:        int32(page.Limit + 1),
+	})
+	if err != nil {
+		log.Printf("failed to list mentioned chirps: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	chirps, hasMore := trimPage(chirps, page.Limit)
+	responses, err := cfg.chirpResponses(r.Context(), chirps, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		log.Printf("failed to build chirp responses: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	respondWithChirpPage(w, r, responses, hasMore, page.Limit)
+}
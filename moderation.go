@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/UUest/gohttp/internal/database"
+	"github.com/UUest/gohttp/internal/moderation"
+)
+
+// moderationPollInterval is how often the rules file and table are checked
+// for changes made outside this process.
+const moderationPollInterval = 10 * time.Second
+
+// moderationSource loads the moderation dictionary from the optional rules
+// file and the moderation_rules table and keeps the compiled matcher in the
+// filter up to date.
+type moderationSource struct {
+	filter   *moderation.Filter
+	filePath string
+
+	mu          sync.Mutex
+	fileModTime time.Time
+	dbVersion   database.GetModerationRulesVersionRow
+}
+
+func newModerationSource(filePath string) *moderationSource {
+	empty, _ := moderation.Compile(nil)
+	return &moderationSource{
+		filter:   moderation.NewFilter(empty),
+		filePath: filePath,
+	}
+}
+
+// reload compiles the rules from both sources and swaps them in. File rules
+// come first so they win over table rules matching at the same position.
+// On error the previous matcher stays in place.
+func (s *moderationSource) reload(ctx context.Context, q *database.Queries) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var rules []moderation.Rule
+	var modTime time.Time
+	if s.filePath != "" {
+		info, err := os.Stat(s.filePath)
+		if err != nil {
+			return err
+		}
+		modTime = info.ModTime()
+		rules, err = moderation.LoadFile(s.filePath)
+		if err != nil {
+			return err
+		}
+	}
+	version, err := q.GetModerationRulesVersion(ctx)
+	if err != nil {
+		return err
+	}
+	rows, err := q.ListModerationRules(ctx)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		rules = append(rules, moderationRule(row))
+	}
+	matcher, err := moderation.Compile(rules)
+	if err != nil {
+		return err
+	}
+	s.filter.Store(matcher)
+	s.fileModTime = modTime
+	s.dbVersion = version
+	return nil
+}
+
+// changed reports whether the rules file or table differ from what was
+// last loaded.
+func (s *moderationSource) changed(ctx context.Context, q *database.Queries) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.filePath != "" {
+		info, err := os.Stat(s.filePath)
+		if err != nil {
+			return false, err
+		}
+		if !info.ModTime().Equal(s.fileModTime) {
+			return true, nil
+		}
+	}
+	version, err := q.GetModerationRulesVersion(ctx)
+	if err != nil {
+		return false, err
+	}
+	return version.RuleCount != s.dbVersion.RuleCount || !version.LastUpdatedAt.Equal(s.dbVersion.LastUpdatedAt), nil
+}
+
+// watch polls for changes until ctx is cancelled, so edits to the rules
+// file or rules made through another instance are picked up without a
+// restart.
+func (s *moderationSource) watch(ctx context.Context, q *database.Queries) {
+	ticker := time.NewTicker(moderationPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := s.changed(ctx, q)
+			if err != nil {
+				log.Printf("failed to check moderation rules: %s", err)
+				continue
+			}
+			if !changed {
+				continue
+			}
+			err = s.reload(ctx, q)
+			if err != nil {
+				log.Printf("failed to reload moderation rules: %s", err)
+				continue
+			}
+			log.Printf("reloaded moderation rules")
+		}
+	}
+}
+
+func moderationRule(row database.ModerationRule) moderation.Rule {
+	return moderation.Rule{
+		Pattern:     row.Pattern,
+		MatchType:   moderation.MatchType(row.MatchType),
+		Action:      moderation.Action(row.Action),
+		Replacement: row.Replacement,
+	}
+}
+
+// saveChirpFlags files a report without a reporter for the flag rules a
+// chirp matched, putting it in the moderation queue. A chirp with an open
+// flag keeps that one.
+func saveChirpFlags(ctx context.Context, q *database.Queries, chirp database.Chirp, rules []moderation.Rule) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	patterns := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		patterns = append(patterns, rule.Pattern)
+	}
+	return q.FlagChirp(ctx, database.FlagChirpParams{
+		ChirpID: chirp.ID,
+		Details: strings.Join(patterns, ", "),
+	})
+}
+
+type moderationRuleParameters struct {
+	Id          uuid.UUID `json:"id"`
+	Pattern     string    `json:"pattern"`
+	Match_type  string    `json:"match_type"`
+	Action      string    `json:"action"`
+	Replacement string    `json:"replacement"`
+	Created_at  time.Time `json:"created_at"`
+	Updated_at  time.Time `json:"updated_at"`
+}
+
+func moderationRuleResponse(row database.ModerationRule) moderationRuleParameters {
+	return moderationRuleParameters{
+		Id:          row.ID,
+		Pattern:     row.Pattern,
+		Match_type:  row.MatchType,
+		Action:      row.Action,
+		Replacement: row.Replacement,
+		Created_at:  row.CreatedAt,
+		Updated_at:  row.UpdatedAt,
+	}
+}
+
+// decodeModerationRule reads a rule from the request body, filling in the
+// same defaults as the rules file.
+func decodeModerationRule(r *http.Request) (moderation.Rule, error) {
+	rule := moderation.Rule{}
+	err := json.NewDecoder(r.Body).Decode(&rule)
+	if err != nil {
+		return moderation.Rule{}, err
+	}
+	if rule.MatchType == "" {
+		rule.MatchType = moderation.MatchWord
+	}
+	if rule.Action == "" {
+		rule.Action = moderation.ActionMask
+	}
+	return rule, rule.Validate()
+}
+
+func (cfg *apiConfig) reloadModeration(ctx context.Context) {
+	err := cfg.moderation.reload(ctx, cfg.dbQueries)
+	if err != nil {
+		log.Printf("failed to reload moderation rules: %s", err)
+	}
+}
+
+func (cfg *apiConfig) listModerationRules(w http.ResponseWriter, r *http.Request) {
+	rows, err := cfg.dbQueries.ListModerationRules(r.Context())
+	if err != nil {
+		log.Printf("failed to list moderation rules: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	rules := make([]moderationRuleParameters, 0, len(rows))
+	for _, row := range rows {
+		rules = append(rules, moderationRuleResponse(row))
+	}
+	dat, err := json.Marshal(rules)
+	if err != nil {
+		log.Printf("failed to marshal moderation rules: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, dat)
+}
+
+func (cfg *apiConfig) createModerationRule(w http.ResponseWriter, r *http.Request) {
+	rule, err := decodeModerationRule(r)
+	if err != nil {
+		log.Printf("invalid moderation rule: %s", err)
+		respondWithError(w, http.StatusBadRequest, []byte(err.Error()))
+		return
+	}
+	row, err := cfg.dbQueries.CreateModerationRule(r.Context(), database.CreateModerationRuleParams{
+		Pattern:     rule.Pattern,
+		MatchType:   string(rule.MatchType),
+		Action:      string(rule.Action),
+		Replacement: rule.Replacement,
+	})
+	if err != nil {
+		log.Printf("failed to create moderation rule: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	cfg.reloadModeration(r.Context())
+	dat, err := json.Marshal(moderationRuleResponse(row))
+	if err != nil {
+		log.Printf("failed to marshal moderation rule: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, dat)
+}
+
+func (cfg *apiConfig) updateModerationRule(w http.ResponseWriter, r *http.Request) {
+	ruleUUID, err := uuid.Parse(r.PathValue("ruleID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, nil)
+		return
+	}
+	rule, err := decodeModerationRule(r)
+	if err != nil {
+		log.Printf("invalid moderation rule: %s", err)
+		respondWithError(w, http.StatusBadRequest, []byte(err.Error()))
+		return
+	}
+	row, err := cfg.dbQueries.UpdateModerationRule(r.Context(), database.UpdateModerationRuleParams{
+		Pattern:     rule.Pattern,
+		MatchType:   string(rule.MatchType),
+		Action:      string(rule.Action),
+		Replacement: rule.Replacement,
+		ID:          ruleUUID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, nil)
+		return
+	}
+	if err != nil {
+		log.Printf("failed to update moderation rule: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	cfg.reloadModeration(r.Context())
+	dat, err := json.Marshal(moderationRuleResponse(row))
+	if err != nil {
+		log.Printf("failed to marshal moderation rule: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, dat)
+}
+
+func (cfg *apiConfig) deleteModerationRule(w http.ResponseWriter, r *http.Request) {
+	ruleUUID, err := uuid.Parse(r.PathValue("ruleID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, nil)
+		return
+	}
+	deleted, err := cfg.dbQueries.DeleteModerationRule(r.Context(), ruleUUID)
+	if err != nil {
+		log.Printf("failed to delete moderation rule: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, nil)
+		return
+	}
+	cfg.reloadModeration(r.Context())
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pageCursor marks the last row of a page. Rows are ordered by
+// (created_at, id) so the id breaks ties between chirps created in the
+// same instant.
+type pageCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+func encodeCursor(c pageCursor) string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return pageCursor{}, fmt.Errorf("invalid cursor: %w", err)
+	}
+	createdAt, id, found := strings.Cut(string(raw), "|")
+	if !found {
+		return pageCursor{}, fmt.Errorf("invalid cursor")
+	}
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return pageCursor{}, fmt.Errorf("invalid cursor: %w", err)
+	}
+	u, err := uuid.Parse(id)
+	if err != nil {
+		return pageCursor{}, fmt.Errorf("invalid cursor: %w", err)
+	}
+	return pageCursor{CreatedAt: t, ID: u}, nil
+}
+
+func parseLimit(s string) (int, error) {
+	if s == "" {
+		return defaultPageSize, nil
+	}
+	limit, err := strconv.Atoi(s)
+	if err != nil || limit < 1 {
+		return 0, fmt.Errorf("invalid limit: %q", s)
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	return limit, nil
+}
+
+// pageRequest holds the limit and cursor query parameters shared by every
+// paginated endpoint.
+type pageRequest struct {
+	Limit           int
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+}
+
+func parsePageRequest(r *http.Request) (pageRequest, error) {
+	query := r.URL.Query()
+	limit, err := parseLimit(query.Get("limit"))
+	if err != nil {
+		return pageRequest{}, err
+	}
+	page := pageRequest{Limit: limit}
+	if rawCursor := query.Get("cursor"); rawCursor != "" {
+		cursor, err := decodeCursor(rawCursor)
+		if err != nil {
+			return pageRequest{}, err
+		}
+		page.CursorCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		page.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	return page, nil
+}
+
+// setNextLink adds a Link header pointing at the page after the current
+// one, keeping every other query parameter of the request intact.
+func setNextLink(w http.ResponseWriter, r *http.Request, nextCursor string, limit int) {
+	next := url.URL{Path: r.URL.Path}
+	query := r.URL.Query()
+	query.Set("cursor", nextCursor)
+	query.Set("limit", strconv.Itoa(limit))
+	next.RawQuery = query.Encode()
+	w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.String()))
+}
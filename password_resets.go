@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/UUest/gohttp/internal/auth"
+	"github.com/UUest/gohttp/internal/database"
+	"github.com/UUest/gohttp/internal/mail"
+)
+
+const (
+	passwordResetTTL = time.Hour
+	// maxPasswordResetsPerHour caps the reset emails sent to one account.
+	maxPasswordResetsPerHour = 3
+	mailSendTimeout          = 30 * time.Second
+)
+
+// sendMail delivers msg in the background so that slow mail servers don't
+// hold up responses or reveal which requests sent mail.
+func (cfg *apiConfig) sendMail(msg mail.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailSendTimeout)
+		defer cancel()
+		err := cfg.mailer.Send(ctx, msg)
+		if err != nil {
+			log.Printf("failed to send mail: %s", err)
+		}
+	}()
+}
+
+// forgotPassword emails a password reset link. It answers 202 Accepted
+// whether or not the email belongs to an account.
+func (cfg *apiConfig) forgotPassword(w http.ResponseWriter, r *http.Request) {
+	type reqParameters struct {
+		Email string `json:"email"`
+	}
+	reqParams := reqParameters{}
+	err := json.NewDecoder(r.Body).Decode(&reqParams)
+	if err != nil || reqParams.Email == "" {
+		respondWithError(w, http.StatusBadRequest, []byte("email is required"))
+		return
+	}
+	user, err := cfg.dbQueries.GetUserByEmail(r.Context(), reqParams.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if err != nil {
+		log.Printf("failed to get user by email: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	recent, err := cfg.dbQueries.CountRecentPasswordResetTokens(r.Context(), database.CountRecentPasswordResetTokensParams{
+		UserID:        user.ID,
+		WindowSeconds: int32(time.Hour.Seconds()),
+	})
+	if err != nil {
+		log.Printf("failed to count password reset tokens: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	if recent >= maxPasswordResetsPerHour {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	token, err := auth.MakeToken()
+	if err != nil {
+		log.Printf("failed to make password reset token: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	err = cfg.dbQueries.CreatePasswordResetToken(r.Context(), database.CreatePasswordResetTokenParams{
+		UserID:     user.ID,
+		TokenHash:  auth.HashToken(token),
+		TtlSeconds: int32(passwordResetTTL.Seconds()),
+	})
+	if err != nil {
+		log.Printf("failed to create password reset token: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	cfg.sendMail(mail.Message{
+		To:      user.Email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf("Someone asked to reset the password of your Chirpy account.\n\n"+
+			"To choose a new password, send this token with it to POST /api/password/reset within %d minutes:\n\n%s\n\n"+
+			"If it wasn't you, ignore this email; your password stays the same.\n",
+			int(passwordResetTTL.Minutes()), token),
+	})
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// resetPassword sets a new password using a token from forgotPassword.
+// Using a token expires all of the user's other reset tokens and signs
+// them out everywhere.
+func (cfg *apiConfig) resetPassword(w http.ResponseWriter, r *http.Request) {
+	type reqParameters struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	reqParams := reqParameters{}
+	err := json.NewDecoder(r.Body).Decode(&reqParams)
+	if err != nil {
+		log.Printf("failed to decode request body: %s", err)
+		respondWithError(w, http.StatusBadRequest, nil)
+		return
+	}
+	if reqParams.Token == "" || reqParams.Password == "" {
+		respondWithError(w, http.StatusBadRequest, []byte("token and password are required"))
+		return
+	}
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("failed to begin transaction: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+	userID, err := qtx.UsePasswordResetToken(r.Context(), auth.HashToken(reqParams.Token))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusBadRequest, []byte("invalid or expired token"))
+		return
+	}
+	if err != nil {
+		log.Printf("failed to use password reset token: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	// Only hash once the token is known to be valid, so invalid tokens
+	// cannot be used to make the server run bcrypt.
+	hashedPassword, err := auth.HashPassword(reqParams.Password)
+	if err != nil {
+		log.Printf("failed to hash password: %s", err)
+		respondWithError(w, http.StatusBadRequest, []byte("unable to hash password"))
+		return
+	}
+	user, err := qtx.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+		HashedPassword: hashedPassword,
+		ID:             userID,
+	})
+	if err != nil {
+		log.Printf("failed to update user password: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	err = qtx.ExpirePasswordResetTokens(r.Context(), userID)
+	if err != nil {
+		log.Printf("failed to expire password reset tokens: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	err = qtx.RevokeUserRefreshTokens(r.Context(), userID)
+	if err != nil {
+		log.Printf("failed to revoke refresh tokens: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("failed to commit password reset: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	cfg.accountCache.Delete(userID)
+	err = cfg.dbQueries.ClearLoginFailures(r.Context(), accountLoginSubject(user.Email))
+	if err != nil {
+		log.Printf("failed to clear login failures: %s", err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
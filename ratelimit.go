@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/UUest/gohttp/internal/auth"
+	"github.com/UUest/gohttp/internal/ratelimit"
+)
+
+type rateLimitKey int
+
+const (
+	// rateLimitByIP counts requests per client address.
+	rateLimitByIP rateLimitKey = iota
+	// rateLimitByUser counts requests per access token subject. Requests
+	// without a valid token are counted per client address.
+	rateLimitByUser
+)
+
+// rateLimitPolicy is the limit applied to one route.
+type rateLimitPolicy struct {
+	name  string
+	limit ratelimit.Limit
+	key   rateLimitKey
+	// disabled is set when the limit was turned off with "off".
+	disabled bool
+}
+
+// routeLimit returns the policy for the named route. RATE_LIMIT_<NAME>,
+// e.g. RATE_LIMIT_LOGIN=5/1m, overrides the default limit, and "off"
+// disables it.
+func routeLimit(name, defaultLimit string, key rateLimitKey) rateLimitPolicy {
+	policy := rateLimitPolicy{name: name, key: key}
+	raw := defaultLimit
+	envName := "RATE_LIMIT_" + strings.ToUpper(name)
+	if v := os.Getenv(envName); v != "" {
+		raw = v
+	}
+	if raw == "off" {
+		policy.disabled = true
+		return policy
+	}
+	limit, err := ratelimit.ParseLimit(raw)
+	if err != nil {
+		log.Fatalf("invalid %s: %s", envName, err)
+	}
+	policy.limit = limit
+	return policy
+}
+
+// rateLimit rejects requests over the policy's limit with 429 Too Many
+// Requests. Every response carries the RateLimit-* headers. If the store
+// fails the request is let through.
+func (cfg *apiConfig) rateLimit(next http.HandlerFunc, policy rateLimitPolicy) http.HandlerFunc {
+	if policy.disabled {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := policy.name + ":" + cfg.rateLimitSubject(r, policy.key)
+		res, err := cfg.rateLimiter.Take(r.Context(), key, policy.limit)
+		if err != nil {
+			log.Printf("failed to check rate limit: %s", err)
+			next(w, r)
+			return
+		}
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		w.Header().Set("RateLimit-Reset", ceilSeconds(res.Reset))
+		if !res.Allowed {
+			w.Header().Set("Retry-After", ceilSeconds(res.RetryAfter))
+			respondWithError(w, http.StatusTooManyRequests, []byte("rate limit exceeded"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (cfg *apiConfig) rateLimitSubject(r *http.Request, key rateLimitKey) string {
+	if key == rateLimitByUser {
+		// Only the signature is checked here. The handler still
+		// authenticates the request fully.
+		token, err := auth.GetBearerToken(r.Header)
+		if err == nil {
+			claims, err := cfg.jwtKeys.ParseJWT(token)
+			if err == nil {
+				return "user:" + claims.Subject
+			}
+		}
+	}
+	return "ip:" + cfg.clientIP(r)
+}
+
+// clientIP returns the address of the client. Behind a reverse proxy,
+// with TRUST_PROXY set, it is the address the proxy appended to
+// X-Forwarded-For.
+func (cfg *apiConfig) clientIP(r *http.Request) string {
+	if cfg.trustProxy {
+		forwarded := r.Header.Values("X-Forwarded-For")
+		if len(forwarded) > 0 {
+			hops := strings.Split(forwarded[len(forwarded)-1], ",")
+			if ip := strings.TrimSpace(hops[len(hops)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func ceilSeconds(d time.Duration) string {
+	return fmt.Sprint(int64(math.Ceil(d.Seconds())))
+}
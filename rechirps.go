@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/UUest/gohttp/internal/database"
+)
+
+// feedEntry is one item of a feed that mixes chirps and rechirps. For a
+// plain chirp EntryID equals ChirpID; for a rechirp EntryID is the rechirp's
+// own ID and RechirpedBy is the user who shared ChirpID.
+type feedEntry struct {
+	EntryID     uuid.UUID
+	CreatedAt   time.Time
+	ChirpID     uuid.UUID
+	RechirpedBy uuid.NullUUID
+}
+
+// feedResponses loads the chirps behind entries and converts them to their
+// JSON shape, wrapping rechirped chirps in a rechirp_of envelope. Entries
+// whose chirp has been deleted in the meantime are skipped.
+func (cfg *apiConfig) feedResponses(ctx context.Context, entries []feedEntry, viewerID uuid.NullUUID) ([]chirpResponse, error) {
+	responses := []chirpResponse{}
+	if len(entries) == 0 {
+		return responses, nil
+	}
+	chirpIDs := make([]uuid.UUID, 0, len(entries))
+	for _, entry := range entries {
+		chirpIDs = append(chirpIDs, entry.ChirpID)
+	}
+	chirps, err := cfg.dbQueries.GetChirpsByIDs(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	chirpResponses, err := cfg.chirpResponses(ctx, chirps, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]chirpResponse, len(chirpResponses))
+	for _, response := range chirpResponses {
+		byID[response.Id] = response
+	}
+	for _, entry := range entries {
+		chirp, ok := byID[entry.ChirpID]
+		if !ok {
+			continue
+		}
+		if !entry.RechirpedBy.Valid {
+			responses = append(responses, chirp)
+			continue
+		}
+		responses = append(responses, chirpResponse{
+			Id:         entry.EntryID,
+			Created_at: entry.CreatedAt,
+			Updated_at: entry.CreatedAt,
+			User_id:    entry.RechirpedBy.UUID,
+			Rechirp_of: &chirp,
+		})
+	}
+	return responses, nil
+}
+
+func (cfg *apiConfig) rechirp(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, nil)
+		return
+	}
+	_, err = cfg.dbQueries.GetVisibleChirpByID(r.Context(), chirpUUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, nil)
+		return
+	}
+	if err != nil {
+		log.Printf("failed to get chirp by id: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	err = cfg.dbQueries.CreateRechirp(r.Context(), database.CreateRechirpParams{
+		UserID:  userID,
+		ChirpID: chirpUUID,
+	})
+	if err != nil {
+		log.Printf("failed to create rechirp: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (cfg *apiConfig) undoRechirp(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, nil)
+		return
+	}
+	err = cfg.dbQueries.DeleteRechirp(r.Context(), database.DeleteRechirpParams{
+		UserID:  userID,
+		ChirpID: chirpUUID,
+	})
+	if err != nil {
+		log.Printf("failed to delete rechirp: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/UUest/gohttp/internal/database"
+)
+
+const (
+	maxReportDetailsLength = 500
+	// reportReasonFlagged marks reports filed by the moderation dictionary
+	// rather than by a user.
+	reportReasonFlagged = "flagged"
+)
+
+// reportReasons are the reason codes users can pick when reporting a chirp.
+var reportReasons = map[string]bool{
+	"spam":           true,
+	"harassment":     true,
+	"hate":           true,
+	"violence":       true,
+	"sexual":         true,
+	"self_harm":      true,
+	"misinformation": true,
+	"other":          true,
+}
+
+// Actions a moderator can take on a reported chirp. Every decision resolves
+// the chirp's open reports.
+const (
+	decisionDismiss = "dismiss"
+	decisionHide    = "hide"
+	decisionUnhide  = "unhide"
+	decisionRemove  = "remove"
+)
+
+type reportParameters struct {
+	Id          uuid.UUID  `json:"id"`
+	Chirp_id    uuid.UUID  `json:"chirp_id"`
+	Reporter_id *uuid.UUID `json:"reporter_id,omitempty"`
+	Reason      string     `json:"reason"`
+	Details     string     `json:"details,omitempty"`
+	Created_at  time.Time  `json:"created_at"`
+	Resolved_at *time.Time `json:"resolved_at,omitempty"`
+	Decision_id *uuid.UUID `json:"decision_id,omitempty"`
+}
+
+func reportResponse(report database.Report) reportParameters {
+	return reportParameters{
+		Id:          report.ID,
+		Chirp_id:    report.ChirpID,
+		Reporter_id: nullUUIDPtr(report.ReporterID),
+		Reason:      report.Reason,
+		Details:     report.Details,
+		Created_at:  report.CreatedAt,
+		Resolved_at: nullTimePtr(report.ResolvedAt),
+		Decision_id: nullUUIDPtr(report.DecisionID),
+	}
+}
+
+type decisionParameters struct {
+	Id            uuid.UUID `json:"id"`
+	Chirp_id      uuid.UUID `json:"chirp_id"`
+	Chirp_user_id uuid.UUID `json:"chirp_user_id"`
+	Chirp_body    string    `json:"chirp_body"`
+	Action        string    `json:"action"`
+	Note          string    `json:"note,omitempty"`
+	Created_at    time.Time `json:"created_at"`
+}
+
+func decisionResponse(decision database.ModerationDecision) decisionParameters {
+	return decisionParameters{
+		Id:            decision.ID,
+		Chirp_id:      decision.ChirpID,
+		Chirp_user_id: decision.ChirpUserID,
+		Chirp_body:    decision.ChirpBody,
+		Action:        decision.Action,
+		Note:          decision.Note,
+		Created_at:    decision.CreatedAt,
+	}
+}
+
+func (cfg *apiConfig) reportChirp(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, nil)
+		return
+	}
+	type reqParameters struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	}
+	reqParams := reqParameters{}
+	err = json.NewDecoder(r.Body).Decode(&reqParams)
+	if err != nil {
+		log.Printf("failed to decode request body: %s", err)
+		respondWithError(w, http.StatusBadRequest, nil)
+		return
+	}
+	if !reportReasons[reqParams.Reason] {
+		respondWithError(w, http.StatusBadRequest, []byte("unknown report reason"))
+		return
+	}
+	if len(reqParams.Details) > maxReportDetailsLength {
+		respondWithError(w, http.StatusBadRequest, []byte("report details are too long"))
+		return
+	}
+	chirp, err := cfg.dbQueries.GetVisibleChirpByID(r.Context(), chirpUUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, nil)
+		return
+	}
+	if err != nil {
+		log.Printf("failed to get chirp by id: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	if chirp.UserID == userID {
+		respondWithError(w, http.StatusBadRequest, []byte("cannot report your own chirp"))
+		return
+	}
+	report, err := cfg.dbQueries.CreateReport(r.Context(), database.CreateReportParams{
+		ChirpID:    chirp.ID,
+		ReporterID: uuid.NullUUID{UUID: userID, Valid: true},
+		Reason:     reqParams.Reason,
+		Details:    reqParams.Details,
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, []byte("chirp already reported"))
+		return
+	}
+	if err != nil {
+		log.Printf("failed to create report: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	dat, err := json.Marshal(reportResponse(report))
+	if err != nil {
+		log.Printf("failed to marshal response body: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, dat)
+}
+
+// getModerationQueue lists chirps with open reports, oldest report first,
+// together with those reports.
+func (cfg *apiConfig) getModerationQueue(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePageRequest(r)
+	if err != nil {
+		log.Printf("failed to parse page request: %s", err)
+		respondWithError(w, http.StatusBadRequest, []byte("invalid limit or cursor"))
+		return
+	}
+	rows, err := cfg.dbQueries.ListModerationQueue(r.Context(), database.ListModerationQueueParams{
+		CursorCreatedAt: page.CursorCreatedAt,
+		CursorID:        page.CursorID,
+		PageSize:        int32(page.Limit + 1),
+	})
+	if err != nil {
+		log.Printf("failed to list moderation queue: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	rows, hasMore := trimPage(rows, page.Limit)
+	reports, err := cfg.openReportsByChirp(r.Context(), rows)
+	if err != nil {
+		log.Printf("failed to get open reports: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+
+	type queueItem struct {
+		Chirp_id          uuid.UUID          `json:"chirp_id"`
+		Body              string             `json:"body"`
+		User_id           uuid.UUID          `json:"user_id"`
+		Hidden_at         *time.Time         `json:"hidden_at,omitempty"`
+		Report_count      int64              `json:"report_count"`
+		First_reported_at time.Time          `json:"first_reported_at"`
+		Reports           []reportParameters `json:"reports"`
+	}
+	items := make([]queueItem, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, queueItem{
+			Chirp_id:          row.ID,
+			Body:              row.Body,
+			User_id:           row.UserID,
+			Hidden_at:         nullTimePtr(row.HiddenAt),
+			Report_count:      row.ReportCount,
+			First_reported_at: row.FirstReportedAt,
+			Reports:           reports[row.ID],
+		})
+	}
+	nextCursor := ""
+	if hasMore && len(rows) > 0 {
+		last := rows[len(rows)-1]
+		nextCursor = encodeCursor(pageCursor{CreatedAt: last.FirstReportedAt, ID: last.ID})
+		setNextLink(w, r, nextCursor, page.Limit)
+	}
+	type resParameters struct {
+		Chirps     []queueItem `json:"chirps"`
+		NextCursor string      `json:"next_cursor,omitempty"`
+	}
+	dat, err := json.Marshal(resParameters{
+		Chirps:     items,
+		NextCursor: nextCursor,
+	})
+	if err != nil {
+		log.Printf("failed to marshal response body: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, dat)
+}
+
+func (cfg *apiConfig) openReportsByChirp(ctx context.Context, rows []database.ListModerationQueueRow) (map[uuid.UUID][]reportParameters, error) {
+	byChirp := make(map[uuid.UUID][]reportParameters, len(rows))
+	if len(rows) == 0 {
+		return byChirp, nil
+	}
+	chirpIDs := make([]uuid.UUID, 0, len(rows))
+	for _, row := range rows {
+		chirpIDs = append(chirpIDs, row.ID)
+	}
+	reports, err := cfg.dbQueries.GetOpenReportsByChirpIDs(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, report := range reports {
+		byChirp[report.ChirpID] = append(byChirp[report.ChirpID], reportResponse(report))
+	}
+	return byChirp, nil
+}
+
+// getChirpReports returns every report filed against a chirp, including
+// resolved ones.
+func (cfg *apiConfig) getChirpReports(w http.ResponseWriter, r *http.Request) {
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, nil)
+		return
+	}
+	reports, err := cfg.dbQueries.GetChirpReports(r.Context(), chirpUUID)
+	if err != nil {
+		log.Printf("failed to get chirp reports: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	resParams := make([]reportParameters, 0, len(reports))
+	for _, report := range reports {
+		resParams = append(resParams, reportResponse(report))
+	}
+	dat, err := json.Marshal(resParams)
+	if err != nil {
+		log.Printf("failed to marshal response body: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, dat)
+}
+
+// decideChirp records a moderator's decision on a chirp, applies it and
+// resolves the chirp's open reports. The decision keeps a copy of the chirp
+// so the record survives removal.
+func (cfg *apiConfig) decideChirp(w http.ResponseWriter, r *http.Request) {
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, nil)
+		return
+	}
+	type reqParameters struct {
+		Action string `json:"action"`
+		Note   string `json:"note"`
+	}
+	reqParams := reqParameters{}
+	err = json.NewDecoder(r.Body).Decode(&reqParams)
+	if err != nil {
+		log.Printf("failed to decode request body: %s", err)
+		respondWithError(w, http.StatusBadRequest, nil)
+		return
+	}
+	switch reqParams.Action {
+	case decisionDismiss, decisionHide, decisionUnhide, decisionRemove:
+	default:
+		respondWithError(w, http.StatusBadRequest, []byte("unknown moderation action"))
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("failed to begin transaction: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	chirp, err := qtx.GetChirpByIDForUpdate(r.Context(), chirpUUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, nil)
+		return
+	}
+	if err != nil {
+		log.Printf("failed to get chirp by id: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	decision, err := qtx.CreateModerationDecision(r.Context(), database.CreateModerationDecisionParams{
+		ChirpID:     chirp.ID,
+		ChirpUserID: chirp.UserID,
+		ChirpBody:   chirp.Body,
+		Action:      reqParams.Action,
+		Note:        reqParams.Note,
+	})
+	if err != nil {
+		log.Printf("failed to create moderation decision: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	err = qtx.ResolveChirpReports(r.Context(), database.ResolveChirpReportsParams{
+		ChirpID:    chirp.ID,
+		DecisionID: uuid.NullUUID{UUID: decision.ID, Valid: true},
+	})
+	if err != nil {
+		log.Printf("failed to resolve chirp reports: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	switch reqParams.Action {
+	case decisionHide:
+		err = qtx.HideChirp(r.Context(), chirp.ID)
+	case decisionUnhide:
+		err = qtx.UnhideChirp(r.Context(), chirp.ID)
+	case decisionRemove:
+		err = qtx.DeleteChirpByID(r.Context(), chirp.ID)
+	}
+	if err != nil {
+		log.Printf("failed to apply moderation decision: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("failed to commit moderation decision: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	dat, err := json.Marshal(decisionResponse(decision))
+	if err != nil {
+		log.Printf("failed to marshal response body: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, dat)
+}
+
+// getModerationDecisions returns the moderation log, newest first.
+func (cfg *apiConfig) getModerationDecisions(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePageRequest(r)
+	if err != nil {
+		log.Printf("failed to parse page request: %s", err)
+		respondWithError(w, http.StatusBadRequest, []byte("invalid limit or cursor"))
+		return
+	}
+	decisions, err := cfg.dbQueries.ListModerationDecisions(r.Context(), database.ListModerationDecisionsParams{
+		CursorCreatedAt: page.CursorCreatedAt,
+		CursorID:        page.CursorID,
+		PageSize:        int32(page.Limit + 1),
+	})
+	if err != nil {
+		log.Printf("failed to list moderation decisions: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	decisions, hasMore := trimPage(decisions, page.Limit)
+	resDecisions := make([]decisionParameters, 0, len(decisions))
+	for _, decision := range decisions {
+		resDecisions = append(resDecisions, decisionResponse(decision))
+	}
+	nextCursor := ""
+	if hasMore && len(decisions) > 0 {
+		last := decisions[len(decisions)-1]
+		nextCursor = encodeCursor(pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+		setNextLink(w, r, nextCursor, page.Limit)
+	}
+	type resParameters struct {
+		Decisions  []decisionParameters `json:"decisions"`
+		NextCursor string               `json:"next_cursor,omitempty"`
+	}
+	dat, err := json.Marshal(resParameters{
+		Decisions:  resDecisions,
+		NextCursor: nextCursor,
+	})
+	if err != nil {
+		log.Printf("failed to marshal response body: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, dat)
+}
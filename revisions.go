@@ -0,0 +1,188 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/UUest/gohttp/internal/database"
+)
+
+func (cfg *apiConfig) updateChirp(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	err = cfg.checkEmailVerified(r.Context(), userID)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, nil)
+		return
+	}
+	type reqParameters struct {
+		Body string `json:"body"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	reqParams := reqParameters{}
+	err = decoder.Decode(&reqParams)
+	if err != nil {
+		log.Printf("failed to decode request body: %s", err)
+		respondWithError(w, http.StatusBadRequest, nil)
+		return
+	}
+	moderated, err := cfg.validateChirpBody(reqParams.Body)
+	if errors.Is(err, errChirpRejected) {
+		respondWithError(w, http.StatusBadRequest, []byte("Chirp violates the moderation policy"))
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, []byte("Chirp is too long"))
+		return
+	}
+	body := moderated.Text
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("failed to begin transaction: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	chirp, err := qtx.GetChirpByIDForUpdate(r.Context(), chirpUUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, nil)
+		return
+	}
+	if err != nil {
+		log.Printf("failed to get chirp by id: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	if chirp.UserID != userID {
+		respondWithError(w, http.StatusForbidden, nil)
+		return
+	}
+	if chirp.Body != body {
+		_, err = qtx.CreateChirpRevision(r.Context(), database.CreateChirpRevisionParams{
+			ChirpID: chirp.ID,
+			Body:    chirp.Body,
+		})
+		if err != nil {
+			log.Printf("failed to create chirp revision: %s", err)
+			respondWithError(w, http.StatusInternalServerError, nil)
+			return
+		}
+		chirp, err = qtx.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{
+			Body: body,
+			ID:   chirp.ID,
+		})
+		if err != nil {
+			log.Printf("failed to update chirp body: %s", err)
+			respondWithError(w, http.StatusInternalServerError, nil)
+			return
+		}
+		err = saveChirpHashtags(r.Context(), qtx, chirp)
+		if err != nil {
+			log.Printf("failed to save chirp hashtags: %s", err)
+			respondWithError(w, http.StatusInternalServerError, nil)
+			return
+		}
+		err = saveChirpMentions(r.Context(), qtx, chirp)
+		if err != nil {
+			log.Printf("failed to save chirp mentions: %s", err)
+			respondWithError(w, http.StatusInternalServerError, nil)
+			return
+		}
+		err = saveChirpFlags(r.Context(), qtx, chirp, moderated.Flagged)
+		if err != nil {
+			log.Printf("failed to save chirp flags: %s", err)
+			respondWithError(w, http.StatusInternalServerError, nil)
+			return
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("failed to commit chirp update: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+
+	resParams, err := cfg.chirpResponses(r.Context(), []database.Chirp{chirp}, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		log.Printf("failed to build chirp response: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	dat, err := json.Marshal(resParams[0])
+	if err != nil {
+		log.Printf("failed to marshal response body: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, dat)
+}
+
+func (cfg *apiConfig) getChirpRevisions(w http.ResponseWriter, r *http.Request) {
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, nil)
+		return
+	}
+	chirp, err := cfg.dbQueries.GetVisibleChirpByID(r.Context(), chirpUUID)
+	if err != nil {
+		log.Printf("failed to get chirp by id: %s", err)
+		respondWithError(w, http.StatusNotFound, nil)
+		return
+	}
+	revisions, err := cfg.dbQueries.GetChirpRevisions(r.Context(), chirp.ID)
+	if err != nil {
+		log.Printf("failed to get chirp revisions: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	// Each revision holds a body that was replaced by an edit at
+	// replaced_at. The current body is the chirp itself.
+	type revisionParameters struct {
+		Id          uuid.UUID `json:"id"`
+		Body        string    `json:"body"`
+		Replaced_at time.Time `json:"replaced_at"`
+	}
+	type resParameters struct {
+		Chirp_id   uuid.UUID            `json:"chirp_id"`
+		Body       string               `json:"body"`
+		Updated_at time.Time            `json:"updated_at"`
+		Revisions  []revisionParameters `json:"revisions"`
+	}
+	resParams := resParameters{
+		Chirp_id:   chirp.ID,
+		Body:       chirp.Body,
+		Updated_at: chirp.UpdatedAt,
+		Revisions:  []revisionParameters{},
+	}
+	for _, revision := range revisions {
+		resParams.Revisions = append(resParams.Revisions, revisionParameters{
+			Id:          revision.ID,
+			Body:        revision.Body,
+			Replaced_at: revision.CreatedAt,
+		})
+	}
+	dat, err := json.Marshal(resParams)
+	if err != nil {
+		log.Printf("failed to marshal response body: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, dat)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/UUest/gohttp/internal/auth"
+	"github.com/UUest/gohttp/internal/database"
+)
+
+// requireRole only lets requests through from callers holding one of
+// roles. Admins pass every check. The caller's claims are stored in the
+// request context for the handler.
+func (cfg *apiConfig) requireRole(next http.HandlerFunc, roles ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := cfg.authenticateClaims(r, false)
+		if err != nil {
+			respondWithAuthError(w, err)
+			return
+		}
+		if !auth.HasRole(claims.Role, roles...) {
+			respondWithError(w, http.StatusForbidden, nil)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), claimsContextKey{}, claims)))
+	}
+}
+
+func validRole(role string) bool {
+	switch role {
+	case auth.RoleUser, auth.RoleModerator, auth.RoleAdmin:
+		return true
+	}
+	return false
+}
+
+func (cfg *apiConfig) setUserRole(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, nil)
+		return
+	}
+	type reqParameters struct {
+		Role string `json:"role"`
+	}
+	reqParams := reqParameters{}
+	err = json.NewDecoder(r.Body).Decode(&reqParams)
+	if err != nil {
+		log.Printf("failed to decode request body: %s", err)
+		respondWithError(w, http.StatusBadRequest, nil)
+		return
+	}
+	if !validRole(reqParams.Role) {
+		respondWithError(w, http.StatusBadRequest, []byte("role must be user, moderator or admin"))
+		return
+	}
+	user, err := cfg.dbQueries.UpdateUserRole(r.Context(), database.UpdateUserRoleParams{
+		Role: reqParams.Role,
+		ID:   userUUID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, nil)
+		return
+	}
+	if err != nil {
+		log.Printf("failed to update user role: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	cfg.accountCache.Delete(user.ID)
+	type resParameters struct {
+		Id          uuid.UUID `json:"id"`
+		Created_at  time.Time `json:"created_at"`
+		Updated_at  time.Time `json:"updated_at"`
+		Email       string    `json:"email"`
+		IsChirpyRed bool      `json:"is_chirpy_red"`
+		Handle      string    `json:"handle,omitempty"`
+		Role        string    `json:"role"`
+	}
+	dat, err := json.Marshal(resParameters{
+		Id:          user.ID,
+		Created_at:  user.CreatedAt,
+		Updated_at:  user.UpdatedAt,
+		Email:       user.Email,
+		IsChirpyRed: user.ChirpyRed.Bool,
+		Handle:      user.Handle.String,
+		Role:        user.Role,
+	})
+	if err != nil {
+		log.Printf("failed to marshal response body: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, dat)
+}
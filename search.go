@@ -0,0 +1,183 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/UUest/gohttp/internal/database"
+)
+
+const maxSearchQueryLength = 256
+
+// Relevance-ranked results are ordered by (rank, created_at, id), so their
+// cursor carries the rank of the last row in front of a regular page cursor.
+func encodeSearchCursor(rank float32, c pageCursor) string {
+	raw := strconv.FormatFloat(float64(rank), 'g', -1, 32) + "|" + encodeCursor(c)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeSearchCursor(s string) (float32, pageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, pageCursor{}, fmt.Errorf("invalid cursor: %w", err)
+	}
+	rawRank, rawCursor, found := strings.Cut(string(raw), "|")
+	if !found {
+		return 0, pageCursor{}, fmt.Errorf("invalid cursor")
+	}
+	rank, err := strconv.ParseFloat(rawRank, 32)
+	if err != nil {
+		return 0, pageCursor{}, fmt.Errorf("invalid cursor: %w", err)
+	}
+	cursor, err := decodeCursor(rawCursor)
+	if err != nil {
+		return 0, pageCursor{}, err
+	}
+	return float32(rank), cursor, nil
+}
+
+// searchChirps runs a full-text search over chirp bodies. q accepts web
+// search syntax: "quoted phrases", OR, and -excluded words. Results are
+// ranked by relevance unless sort=recent.
+func (cfg *apiConfig) searchChirps(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := strings.TrimSpace(query.Get("q"))
+	if q == "" || len(q) > maxSearchQueryLength {
+		respondWithError(w, http.StatusBadRequest, []byte("q is required and must be at most 256 bytes"))
+		return
+	}
+	sortOrder := query.Get("sort")
+	if sortOrder == "" {
+		sortOrder = "relevance"
+	}
+	if sortOrder != "relevance" && sortOrder != "recent" {
+		respondWithError(w, http.StatusBadRequest, []byte("sort must be relevance or recent"))
+		return
+	}
+	limit, err := parseLimit(query.Get("limit"))
+	if err != nil {
+		log.Printf("failed to parse limit: %s", err)
+		respondWithError(w, http.StatusBadRequest, []byte("invalid limit"))
+		return
+	}
+	authorID := uuid.NullUUID{}
+	if rawAuthor := query.Get("author_id"); rawAuthor != "" {
+		id, err := uuid.Parse(rawAuthor)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, []byte("invalid author_id"))
+			return
+		}
+		authorID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	since, err := parseTimeParam(query.Get("since"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, []byte("since must be an RFC 3339 timestamp"))
+		return
+	}
+	until, err := parseTimeParam(query.Get("until"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, []byte("until must be an RFC 3339 timestamp"))
+		return
+	}
+	rawCursor := query.Get("cursor")
+
+	var chirps []database.Chirp
+	nextCursor := ""
+	if sortOrder == "recent" {
+		params := database.SearchChirpsByRecencyParams{
+			Query:    q,
+			AuthorID: authorID,
+			Since:    since,
+			Until:    until,
+			PageSize: int32(limit + 1),
+		}
+		if rawCursor != "" {
+			cursor, err := decodeCursor(rawCursor)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, []byte("invalid cursor"))
+				return
+			}
+			params.CursorCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+			params.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+		}
+		rows, err := cfg.dbQueries.SearchChirpsByRecency(r.Context(), params)
+		if err != nil {
+			log.Printf("failed to search chirps: %s", err)
+			respondWithError(w, http.StatusInternalServerError, nil)
+			return
+		}
+		rows, hasMore := trimPage(rows, limit)
+		if hasMore {
+			last := rows[len(rows)-1]
+			nextCursor = encodeCursor(pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+		}
+		chirps = rows
+	} else {
+		params := database.SearchChirpsByRelevanceParams{
+			Query:    q,
+			AuthorID: authorID,
+			Since:    since,
+			Until:    until,
+			PageSize: int32(limit + 1),
+		}
+		if rawCursor != "" {
+			rank, cursor, err := decodeSearchCursor(rawCursor)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, []byte("invalid cursor"))
+				return
+			}
+			params.CursorRank = sql.NullFloat64{Float64: float64(rank), Valid: true}
+			params.CursorCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+			params.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+		}
+		rows, err := cfg.dbQueries.SearchChirpsByRelevance(r.Context(), params)
+		if err != nil {
+			log.Printf("failed to search chirps: %s", err)
+			respondWithError(w, http.StatusInternalServerError, nil)
+			return
+		}
+		rows, hasMore := trimPage(rows, limit)
+		if hasMore {
+			last := rows[len(rows)-1]
+			nextCursor = encodeSearchCursor(last.Rank, pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+		}
+		for _, row := range rows {
+			chirps = append(chirps, database.Chirp{
+				ID:        row.ID,
+				CreatedAt: row.CreatedAt,
+				UpdatedAt: row.UpdatedAt,
+				Body:      row.Body,
+				UserID:    row.UserID,
+				InReplyTo: row.InReplyTo,
+				QuoteOf:   row.QuoteOf,
+				HiddenAt:  row.HiddenAt,
+			})
+		}
+	}
+	responses, err := cfg.chirpResponses(r.Context(), chirps, cfg.viewerID(r))
+	if err != nil {
+		log.Printf("failed to build chirp responses: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	writeChirpPage(w, r, responses, nextCursor, limit)
+}
+
+func parseTimeParam(s string) (sql.NullTime, error) {
+	if s == "" {
+		return sql.NullTime{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return sql.NullTime{}, err
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}, nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/UUest/gohttp/internal/database"
+)
+
+const maxUserAgentLength = 512
+
+// requestUserAgent returns the User-Agent of r, shortened to fit a
+// session record.
+func requestUserAgent(r *http.Request) string {
+	ua := r.UserAgent()
+	if len(ua) > maxUserAgentLength {
+		ua = strings.ToValidUTF8(ua[:maxUserAgentLength], "")
+	}
+	return ua
+}
+
+// getSessions lists the caller's sessions, most recently used first. A
+// session is everything descending from one login, so its id stays the
+// same as its refresh token rotates.
+func (cfg *apiConfig) getSessions(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	sessions, err := cfg.dbQueries.ListUserSessions(r.Context(), userID)
+	if err != nil {
+		log.Printf("failed to list sessions: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	type sessionParameters struct {
+		Id           uuid.UUID `json:"id"`
+		Created_at   time.Time `json:"created_at"`
+		Last_used_at time.Time `json:"last_used_at"`
+		Expires_at   time.Time `json:"expires_at"`
+		User_agent   string    `json:"user_agent"`
+		Ip           string    `json:"ip"`
+	}
+	resSessions := make([]sessionParameters, 0, len(sessions))
+	for _, session := range sessions {
+		resSessions = append(resSessions, sessionParameters{
+			Id:           session.FamilyID,
+			Created_at:   session.StartedAt,
+			Last_used_at: session.LastUsedAt,
+			Expires_at:   session.ExpiresAt,
+			User_agent:   session.UserAgent,
+			Ip:           session.Ip,
+		})
+	}
+	dat, err := json.Marshal(resSessions)
+	if err != nil {
+		log.Printf("failed to marshal response body: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, dat)
+}
+
+// deleteSession signs one of the caller's sessions out. Access tokens
+// already issued to it stay valid until they expire.
+func (cfg *apiConfig) deleteSession(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	sessionID, err := uuid.Parse(r.PathValue("sessionID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, nil)
+		return
+	}
+	n, err := cfg.dbQueries.RevokeUserRefreshTokenFamily(r.Context(), database.RevokeUserRefreshTokenFamilyParams{
+		UserID:   userID,
+		FamilyID: sessionID,
+	})
+	if err != nil {
+		log.Printf("failed to revoke session: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// logoutAll signs the caller out of every session.
+func (cfg *apiConfig) logoutAll(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	err = cfg.dbQueries.RevokeUserRefreshTokens(r.Context(), userID)
+	if err != nil {
+		log.Printf("failed to revoke refresh tokens: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,39 @@
+-- name: BlockUser :exec
+INSERT INTO user_blocks (blocker_id, blocked_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: UnblockUser :exec
+DELETE FROM user_blocks
+WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: MuteUser :exec
+INSERT INTO user_mutes (muter_id, muted_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: UnmuteUser :exec
+DELETE FROM user_mutes
+WHERE muter_id = $1 AND muted_id = $2;
+
+-- name: IsBlockedBetween :one
+SELECT EXISTS (
+    SELECT 1
+    FROM user_blocks
+    WHERE (blocker_id = sqlc.arg('user_a') AND blocked_id = sqlc.arg('user_b'))
+       OR (blocker_id = sqlc.arg('user_b') AND blocked_id = sqlc.arg('user_a'))
+);
+
+-- name: GetBlockedUserIDs :many
+SELECT blocked_id AS user_id
+FROM user_blocks
+WHERE blocker_id = sqlc.arg('user_id')
+UNION
+SELECT blocker_id
+FROM user_blocks
+WHERE blocked_id = sqlc.arg('user_id');
+
+-- name: DeleteFollowsBetween :exec
+DELETE FROM follows
+WHERE (follower_id = sqlc.arg('user_a') AND followee_id = sqlc.arg('user_b'))
+   OR (follower_id = sqlc.arg('user_b') AND followee_id = sqlc.arg('user_a'));
@@ -1,6 +1,6 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
-VALUES ($1, NOW(), NOW(), $2, $3)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to, quote_of)
+VALUES ($1, NOW(), NOW(), $2, $3, $4, $5)
 RETURNING *;
 
 -- name: GetChirps :many
@@ -19,6 +19,107 @@ SELECT *
 FROM chirps
 WHERE id = $1;
 
+-- name: GetVisibleChirpByID :one
+SELECT *
+FROM chirps
+WHERE id = $1
+  AND hidden_at IS NULL;
+
 -- name: DeleteChirpByID :exec
 DELETE FROM chirps
 WHERE id = $1;
+
+-- name: ListChirpsAsc :many
+SELECT *
+FROM chirps
+WHERE hidden_at IS NULL
+  AND user_id NOT IN (SELECT author_id FROM viewer_hidden_authors WHERE viewer_id = sqlc.narg('viewer_id')::uuid)
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY created_at, id
+LIMIT sqlc.arg('page_size');
+
+-- name: ListChirpsDesc :many
+SELECT *
+FROM chirps
+WHERE hidden_at IS NULL
+  AND user_id NOT IN (SELECT author_id FROM viewer_hidden_authors WHERE viewer_id = sqlc.narg('viewer_id')::uuid)
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: GetChirpByIDForUpdate :one
+SELECT *
+FROM chirps
+WHERE id = $1
+FOR UPDATE;
+
+-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $1,
+    updated_at = NOW()
+WHERE id = $2
+RETURNING *;
+
+-- name: GetChirpThreadRootID :one
+WITH RECURSIVE ancestors AS (
+    SELECT chirps.id, chirps.in_reply_to
+    FROM chirps
+    WHERE chirps.id = sqlc.arg('chirp_id')
+    UNION ALL
+    SELECT chirps.id, chirps.in_reply_to
+    FROM chirps
+    JOIN ancestors ON chirps.id = ancestors.in_reply_to
+)
+SELECT ancestors.id
+FROM ancestors
+WHERE ancestors.in_reply_to IS NULL;
+
+-- name: GetChirpThread :many
+WITH RECURSIVE thread AS (
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, 0::int AS depth
+    FROM chirps
+    WHERE chirps.id = sqlc.arg('chirp_id')
+      AND chirps.hidden_at IS NULL
+    UNION ALL
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, thread.depth + 1
+    FROM chirps
+    JOIN thread ON chirps.in_reply_to = thread.id
+    WHERE chirps.hidden_at IS NULL
+      AND chirps.user_id NOT IN (SELECT author_id FROM viewer_hidden_authors WHERE viewer_id = sqlc.narg('viewer_id')::uuid)
+)
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, depth
+FROM thread
+ORDER BY depth, created_at, id;
+
+-- name: GetChirpsByIDs :many
+SELECT *
+FROM chirps
+WHERE id = ANY(sqlc.arg('ids')::uuid[])
+  AND hidden_at IS NULL;
+
+-- name: HideChirp :exec
+UPDATE chirps
+SET hidden_at = NOW()
+WHERE id = $1;
+
+-- name: UnhideChirp :exec
+UPDATE chirps
+SET hidden_at = NULL
+WHERE id = $1;
+
+-- name: ListUserChirps :many
+SELECT *
+FROM chirps
+WHERE user_id = sqlc.arg('user_id')
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_size');
@@ -0,0 +1,40 @@
+-- name: CreateEmailVerificationToken :exec
+INSERT INTO email_verification_tokens (id, created_at, user_id, email, token_hash, expires_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    sqlc.arg('user_id'),
+    sqlc.arg('email'),
+    sqlc.arg('token_hash'),
+    NOW() + make_interval(secs => sqlc.arg('ttl_seconds')::int)
+);
+
+-- name: UseEmailVerificationToken :one
+UPDATE email_verification_tokens
+SET used_at = NOW()
+WHERE token_hash = $1
+  AND used_at IS NULL
+  AND expires_at > NOW()
+RETURNING user_id, email;
+
+-- name: ExpireEmailVerificationTokens :exec
+UPDATE email_verification_tokens
+SET used_at = NOW()
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: CountRecentEmailVerificationTokens :one
+SELECT COUNT(*)
+FROM email_verification_tokens
+WHERE user_id = sqlc.arg('user_id')
+  AND created_at > NOW() - make_interval(secs => sqlc.arg('window_seconds')::int);
+
+-- name: GetPendingEmail :one
+SELECT email_verification_tokens.email
+FROM email_verification_tokens
+JOIN users ON users.id = email_verification_tokens.user_id
+WHERE email_verification_tokens.user_id = $1
+  AND email_verification_tokens.email <> users.email
+  AND email_verification_tokens.used_at IS NULL
+  AND email_verification_tokens.expires_at > NOW()
+ORDER BY email_verification_tokens.created_at DESC
+LIMIT 1;
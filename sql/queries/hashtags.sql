@@ -0,0 +1,29 @@
+-- name: AddChirpHashtag :exec
+INSERT INTO chirp_hashtags (chirp_id, tag, created_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (chirp_id, tag) DO NOTHING;
+
+-- name: DeleteChirpHashtags :exec
+DELETE FROM chirp_hashtags
+WHERE chirp_id = $1;
+
+-- name: ListHashtagChirps :many
+SELECT chirps.*
+FROM chirps
+JOIN chirp_hashtags ON chirp_hashtags.chirp_id = chirps.id
+WHERE chirp_hashtags.tag = sqlc.arg('tag')
+  AND chirps.hidden_at IS NULL
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: GetTrendingHashtags :many
+SELECT tag, COUNT(*) AS chirp_count
+FROM chirp_hashtags
+WHERE created_at >= NOW() - make_interval(secs => sqlc.arg('window_seconds')::int)
+GROUP BY tag
+ORDER BY chirp_count DESC, tag
+LIMIT sqlc.arg('max_tags');
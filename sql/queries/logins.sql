@@ -0,0 +1,51 @@
+-- name: GetLoginRetryAfter :one
+SELECT COALESCE(CEIL(EXTRACT(EPOCH FROM MAX(blocked_until) - NOW())), 0)::int AS retry_after_seconds
+FROM login_failures
+WHERE subject = ANY(sqlc.arg('subjects')::text[])
+  AND blocked_until > NOW();
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_failures (subject, failures, last_failed_at)
+VALUES (sqlc.arg('subject'), 1, NOW())
+ON CONFLICT (subject) DO UPDATE
+SET failures = CASE
+        WHEN login_failures.last_failed_at < NOW() - make_interval(secs => sqlc.arg('window_seconds')::int) THEN 1
+        ELSE login_failures.failures + 1
+    END,
+    last_failed_at = NOW()
+RETURNING failures;
+
+-- name: BlockLogin :exec
+UPDATE login_failures
+SET blocked_until = NOW() + make_interval(secs => sqlc.arg('seconds')::int),
+    failures = CASE WHEN sqlc.arg('reset_failures')::boolean THEN 0 ELSE failures END
+WHERE subject = sqlc.arg('subject');
+
+-- name: ClearLoginFailures :exec
+DELETE FROM login_failures
+WHERE subject = $1;
+
+-- name: CreateLoginLockout :one
+INSERT INTO login_lockouts (id, created_at, kind, email, user_id, ip, failures, locked_until)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    sqlc.arg('kind'),
+    sqlc.arg('email'),
+    sqlc.narg('user_id'),
+    sqlc.arg('ip'),
+    sqlc.arg('failures'),
+    NOW() + make_interval(secs => sqlc.arg('seconds')::int)
+)
+RETURNING *;
+
+-- name: ListLoginLockouts :many
+SELECT *, (locked_until > NOW())::boolean AS active
+FROM login_lockouts
+WHERE (sqlc.narg('user_id')::uuid IS NULL OR user_id = sqlc.narg('user_id')::uuid)
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_size');
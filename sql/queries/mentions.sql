@@ -0,0 +1,30 @@
+-- name: AddChirpMention :exec
+INSERT INTO chirp_mentions (chirp_id, user_id, handle, start_index, end_index, created_at)
+VALUES ($1, $2, $3, $4, $5, NOW());
+
+-- name: DeleteChirpMentions :exec
+DELETE FROM chirp_mentions
+WHERE chirp_id = $1;
+
+-- name: GetChirpMentions :many
+SELECT chirp_id, user_id, handle, start_index, end_index
+FROM chirp_mentions
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY chirp_id, start_index;
+
+-- name: ListMentionedChirps :many
+SELECT chirps.*
+FROM chirps
+WHERE chirps.id IN (
+    SELECT chirp_mentions.chirp_id
+    FROM chirp_mentions
+    WHERE chirp_mentions.user_id = sqlc.arg('user_id')
+  )
+  AND chirps.hidden_at IS NULL
+  AND chirps.user_id NOT IN (SELECT author_id FROM viewer_hidden_authors WHERE viewer_id = sqlc.arg('user_id'))
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('page_size');
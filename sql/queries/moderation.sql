@@ -0,0 +1,32 @@
+-- name: ListModerationRules :many
+SELECT * FROM moderation_rules
+ORDER BY created_at, id;
+
+-- name: GetModerationRulesVersion :one
+SELECT
+    COUNT(*) AS rule_count,
+    COALESCE(MAX(updated_at), 'epoch'::timestamp)::timestamp AS last_updated_at
+FROM moderation_rules;
+
+-- name: CreateModerationRule :one
+INSERT INTO moderation_rules (id, created_at, updated_at, pattern, match_type, action, replacement)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+RETURNING *;
+
+-- name: UpdateModerationRule :one
+UPDATE moderation_rules
+SET pattern = $1, match_type = $2, action = $3, replacement = $4, updated_at = NOW()
+WHERE id = $5
+RETURNING *;
+
+-- name: DeleteModerationRule :execrows
+DELETE FROM moderation_rules
+WHERE id = $1;
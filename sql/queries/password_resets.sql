@@ -0,0 +1,28 @@
+-- name: CreatePasswordResetToken :exec
+INSERT INTO password_reset_tokens (id, created_at, user_id, token_hash, expires_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    sqlc.arg('user_id'),
+    sqlc.arg('token_hash'),
+    NOW() + make_interval(secs => sqlc.arg('ttl_seconds')::int)
+);
+
+-- name: UsePasswordResetToken :one
+UPDATE password_reset_tokens
+SET used_at = NOW()
+WHERE token_hash = $1
+  AND used_at IS NULL
+  AND expires_at > NOW()
+RETURNING user_id;
+
+-- name: ExpirePasswordResetTokens :exec
+UPDATE password_reset_tokens
+SET used_at = NOW()
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: CountRecentPasswordResetTokens :one
+SELECT COUNT(*)
+FROM password_reset_tokens
+WHERE user_id = sqlc.arg('user_id')
+  AND created_at > NOW() - make_interval(secs => sqlc.arg('window_seconds')::int);
@@ -0,0 +1,80 @@
+-- name: CreateRechirp :exec
+INSERT INTO rechirps (id, user_id, chirp_id, created_at)
+VALUES (gen_random_uuid(), $1, $2, NOW())
+ON CONFLICT (user_id, chirp_id) DO NOTHING;
+
+-- name: DeleteRechirp :exec
+DELETE FROM rechirps
+WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: ListAuthorEntriesAsc :many
+SELECT entries.entry_id, entries.created_at, entries.chirp_id, entries.rechirped_by
+FROM (
+    SELECT chirps.id AS entry_id, chirps.created_at, chirps.id AS chirp_id, NULL::uuid AS rechirped_by
+    FROM chirps
+    WHERE chirps.user_id = sqlc.arg('author_id')
+      AND chirps.hidden_at IS NULL
+      AND chirps.user_id NOT IN (SELECT author_id FROM viewer_hidden_authors WHERE viewer_id = sqlc.narg('viewer_id')::uuid)
+    UNION ALL
+    SELECT rechirps.id, rechirps.created_at, rechirps.chirp_id, rechirps.user_id
+    FROM rechirps
+    JOIN chirps ON chirps.id = rechirps.chirp_id
+    WHERE rechirps.user_id = sqlc.arg('author_id')
+      AND chirps.hidden_at IS NULL
+      AND chirps.user_id NOT IN (SELECT author_id FROM viewer_hidden_authors WHERE viewer_id = sqlc.narg('viewer_id')::uuid)
+) AS entries
+WHERE sqlc.narg('cursor_created_at')::timestamp IS NULL
+  OR (entries.created_at, entries.entry_id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+ORDER BY entries.created_at, entries.entry_id
+LIMIT sqlc.arg('page_size');
+
+-- name: ListAuthorEntriesDesc :many
+SELECT entries.entry_id, entries.created_at, entries.chirp_id, entries.rechirped_by
+FROM (
+    SELECT chirps.id AS entry_id, chirps.created_at, chirps.id AS chirp_id, NULL::uuid AS rechirped_by
+    FROM chirps
+    WHERE chirps.user_id = sqlc.arg('author_id')
+      AND chirps.hidden_at IS NULL
+      AND chirps.user_id NOT IN (SELECT author_id FROM viewer_hidden_authors WHERE viewer_id = sqlc.narg('viewer_id')::uuid)
+    UNION ALL
+    SELECT rechirps.id, rechirps.created_at, rechirps.chirp_id, rechirps.user_id
+    FROM rechirps
+    JOIN chirps ON chirps.id = rechirps.chirp_id
+    WHERE rechirps.user_id = sqlc.arg('author_id')
+      AND chirps.hidden_at IS NULL
+      AND chirps.user_id NOT IN (SELECT author_id FROM viewer_hidden_authors WHERE viewer_id = sqlc.narg('viewer_id')::uuid)
+) AS entries
+WHERE sqlc.narg('cursor_created_at')::timestamp IS NULL
+  OR (entries.created_at, entries.entry_id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+ORDER BY entries.created_at DESC, entries.entry_id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: ListTimelineEntries :many
+SELECT entries.entry_id, entries.created_at, entries.chirp_id, entries.rechirped_by
+FROM (
+    SELECT chirps.id AS entry_id, chirps.created_at, chirps.id AS chirp_id, NULL::uuid AS rechirped_by, chirps.user_id AS actor_id
+    FROM chirps
+    WHERE chirps.hidden_at IS NULL
+      AND chirps.user_id NOT IN (SELECT author_id FROM viewer_hidden_authors WHERE viewer_id = sqlc.arg('viewer_id'))
+    UNION ALL
+    SELECT rechirps.id, rechirps.created_at, rechirps.chirp_id, rechirps.user_id, rechirps.user_id
+    FROM rechirps
+    JOIN chirps ON chirps.id = rechirps.chirp_id
+    WHERE chirps.hidden_at IS NULL
+      AND chirps.user_id NOT IN (SELECT author_id FROM viewer_hidden_authors WHERE viewer_id = sqlc.arg('viewer_id'))
+      AND rechirps.user_id NOT IN (SELECT author_id FROM viewer_hidden_authors WHERE viewer_id = sqlc.arg('viewer_id'))
+) AS entries
+WHERE (
+    entries.actor_id = sqlc.arg('viewer_id')
+    OR entries.actor_id IN (
+      SELECT follows.followee_id
+      FROM follows
+      WHERE follows.follower_id = sqlc.arg('viewer_id')
+    )
+  )
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (entries.created_at, entries.entry_id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY entries.created_at DESC, entries.entry_id DESC
+LIMIT sqlc.arg('page_size');
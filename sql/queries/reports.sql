@@ -0,0 +1,63 @@
+-- name: CreateReport :one
+INSERT INTO reports (id, created_at, chirp_id, reporter_id, reason, details)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4)
+RETURNING *;
+
+-- name: FlagChirp :exec
+INSERT INTO reports (id, created_at, chirp_id, reporter_id, reason, details)
+SELECT gen_random_uuid(), NOW(), sqlc.arg('chirp_id')::uuid, NULL::uuid, 'flagged', sqlc.arg('details')::text
+WHERE NOT EXISTS (
+    SELECT 1
+    FROM reports
+    WHERE reports.chirp_id = sqlc.arg('chirp_id')::uuid
+      AND reports.reporter_id IS NULL
+      AND reports.resolved_at IS NULL
+);
+
+-- name: GetChirpReports :many
+SELECT *
+FROM reports
+WHERE chirp_id = $1
+ORDER BY created_at, id;
+
+-- name: GetOpenReportsByChirpIDs :many
+SELECT *
+FROM reports
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+  AND resolved_at IS NULL
+ORDER BY created_at, id;
+
+-- name: ListModerationQueue :many
+SELECT
+    chirps.id,
+    chirps.body,
+    chirps.user_id,
+    chirps.hidden_at,
+    COUNT(*) AS report_count,
+    MIN(reports.created_at)::timestamp AS first_reported_at
+FROM reports
+JOIN chirps ON chirps.id = reports.chirp_id
+WHERE reports.resolved_at IS NULL
+GROUP BY chirps.id
+HAVING sqlc.narg('cursor_created_at')::timestamp IS NULL
+  OR (MIN(reports.created_at), chirps.id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+ORDER BY first_reported_at, chirps.id
+LIMIT sqlc.arg('page_size');
+
+-- name: ResolveChirpReports :exec
+UPDATE reports
+SET resolved_at = NOW(), decision_id = $2
+WHERE chirp_id = $1 AND resolved_at IS NULL;
+
+-- name: CreateModerationDecision :one
+INSERT INTO moderation_decisions (id, created_at, chirp_id, chirp_user_id, chirp_body, action, note)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: ListModerationDecisions :many
+SELECT *
+FROM moderation_decisions
+WHERE sqlc.narg('cursor_created_at')::timestamp IS NULL
+  OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_size');
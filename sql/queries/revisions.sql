@@ -0,0 +1,10 @@
+-- name: CreateChirpRevision :one
+INSERT INTO chirp_revisions (id, chirp_id, body, created_at)
+VALUES (gen_random_uuid(), $1, $2, NOW())
+RETURNING *;
+
+-- name: GetChirpRevisions :many
+SELECT *
+FROM chirp_revisions
+WHERE chirp_id = $1
+ORDER BY created_at;
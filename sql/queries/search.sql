@@ -0,0 +1,30 @@
+-- name: SearchChirpsByRecency :many
+SELECT chirps.*
+FROM chirps
+WHERE to_tsvector('english', chirps.body) @@ websearch_to_tsquery('english', sqlc.arg('query'))
+  AND chirps.hidden_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id')::uuid)
+  AND (sqlc.narg('since')::timestamp IS NULL OR chirps.created_at >= sqlc.narg('since')::timestamp)
+  AND (sqlc.narg('until')::timestamp IS NULL OR chirps.created_at < sqlc.narg('until')::timestamp)
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: SearchChirpsByRelevance :many
+SELECT chirps.*, ts_rank(to_tsvector('english', chirps.body), websearch_to_tsquery('english', sqlc.arg('query')))::real AS rank
+FROM chirps
+WHERE to_tsvector('english', chirps.body) @@ websearch_to_tsquery('english', sqlc.arg('query'))
+  AND chirps.hidden_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id')::uuid)
+  AND (sqlc.narg('since')::timestamp IS NULL OR chirps.created_at >= sqlc.narg('since')::timestamp)
+  AND (sqlc.narg('until')::timestamp IS NULL OR chirps.created_at < sqlc.narg('until')::timestamp)
+  AND (
+    sqlc.narg('cursor_rank')::real IS NULL
+    OR (ts_rank(to_tsvector('english', chirps.body), websearch_to_tsquery('english', sqlc.arg('query')))::real, chirps.created_at, chirps.id)
+      < (sqlc.narg('cursor_rank')::real, sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY rank DESC, chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('page_size');
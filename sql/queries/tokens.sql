@@ -1,11 +1,15 @@
 -- name: CreateRefreshToken :one
 INSERT INTO refresh_tokens (
-    token,
+    token_hash,
     created_at,
     updated_at,
     user_id,
     expires_at,
-    revoked_at
+    revoked_at,
+    family_id,
+    user_agent,
+    ip,
+    last_used_at
 )
 VALUES (
     $1,
@@ -13,15 +17,71 @@ VALUES (
     NOW(),
     $2,
     NOW() + INTERVAL '60 days',
-    NULL
+    NULL,
+    $3,
+    $4,
+    $5,
+    NOW()
 )
 RETURNING *;
 
 -- name: GetRefreshToken :one
-SELECT * FROM refresh_tokens WHERE token = $1;
+SELECT * FROM refresh_tokens WHERE token_hash = $1;
+
+-- name: GetRefreshTokenForUpdate :one
+SELECT * FROM refresh_tokens WHERE token_hash = $1 FOR UPDATE;
+
+-- name: RotateRefreshToken :exec
+UPDATE refresh_tokens
+SET rotated_at = NOW(),
+    revoked_at = NOW(),
+    updated_at = NOW()
+WHERE token_hash = $1;
+
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL;
 
 -- name: RevokeRefreshToken :exec
 UPDATE refresh_tokens
 SET revoked_at = NOW(),
     updated_at = NOW()
-WHERE token = $1;
+WHERE token_hash = $1;
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: ListUserRefreshTokens :many
+SELECT *
+FROM refresh_tokens
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: ListUserSessions :many
+SELECT
+    refresh_tokens.family_id,
+    (
+        SELECT MIN(family.created_at)
+        FROM refresh_tokens AS family
+        WHERE family.family_id = refresh_tokens.family_id
+    )::timestamp AS started_at,
+    refresh_tokens.last_used_at,
+    refresh_tokens.expires_at,
+    refresh_tokens.user_agent,
+    refresh_tokens.ip
+FROM refresh_tokens
+WHERE refresh_tokens.user_id = $1
+  AND refresh_tokens.revoked_at IS NULL
+  AND refresh_tokens.expires_at > NOW()
+ORDER BY refresh_tokens.last_used_at DESC;
+
+-- name: RevokeUserRefreshTokenFamily :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1 AND family_id = $2 AND revoked_at IS NULL;
@@ -0,0 +1,74 @@
+-- name: SetTOTPPendingSecret :exec
+UPDATE users
+SET totp_pending_secret = $1,
+    updated_at = NOW()
+WHERE id = $2;
+
+-- name: EnableTOTP :execrows
+UPDATE users
+SET totp_secret = totp_pending_secret,
+    totp_pending_secret = NULL,
+    totp_enabled_at = NOW(),
+    totp_last_step = sqlc.arg('step'),
+    updated_at = NOW()
+WHERE id = sqlc.arg('id')
+  AND totp_pending_secret = sqlc.arg('secret');
+
+-- name: DisableTOTP :exec
+UPDATE users
+SET totp_secret = NULL,
+    totp_pending_secret = NULL,
+    totp_enabled_at = NULL,
+    totp_last_step = 0,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: UseTOTPStep :execrows
+UPDATE users
+SET totp_last_step = sqlc.arg('step')
+WHERE id = sqlc.arg('id')
+  AND totp_last_step < sqlc.arg('step');
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM totp_recovery_codes
+WHERE user_id = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO totp_recovery_codes (id, created_at, user_id, code_hash)
+VALUES (gen_random_uuid(), NOW(), $1, $2);
+
+-- name: UseRecoveryCode :execrows
+UPDATE totp_recovery_codes
+SET used_at = NOW()
+WHERE user_id = $1
+  AND code_hash = $2
+  AND used_at IS NULL;
+
+-- name: CountUnusedRecoveryCodes :one
+SELECT COUNT(*)
+FROM totp_recovery_codes
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: CreateLoginChallenge :exec
+INSERT INTO login_challenges (id, created_at, user_id, token_hash, expires_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    sqlc.arg('user_id'),
+    sqlc.arg('token_hash'),
+    NOW() + make_interval(secs => sqlc.arg('ttl_seconds')::int)
+);
+
+-- name: AttemptLoginChallenge :one
+UPDATE login_challenges
+SET attempts = attempts + 1
+WHERE token_hash = sqlc.arg('token_hash')
+  AND used_at IS NULL
+  AND expires_at > NOW()
+  AND attempts < sqlc.arg('max_attempts')
+RETURNING id, user_id;
+
+-- name: CompleteLoginChallenge :execrows
+UPDATE login_challenges
+SET used_at = NOW()
+WHERE id = $1 AND used_at IS NULL;
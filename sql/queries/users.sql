@@ -1,7 +1,7 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
-VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2)
-RETURNING id, created_at, updated_at, email, chirpy_red;
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3)
+RETURNING id, created_at, updated_at, email, chirpy_red, handle;
 
 -- name: DeleteAllUsers :exec
 DELETE FROM users;
@@ -15,15 +15,16 @@ WHERE email = $1;
 SELECT users.*
 FROM users
 JOIN refresh_tokens ON users.id = refresh_tokens.user_id
-WHERE refresh_tokens.token = $1;
+WHERE refresh_tokens.token_hash = $1;
 
 -- name: UpdateUser :one
 UPDATE users
 SET email = $1,
     hashed_password = $2,
+    password_reset_required = password_reset_required AND NOT $3::boolean,
     updated_at = NOW()
-WHERE id = $3
-RETURNING id, created_at, updated_at, email, chirpy_red;
+WHERE id = $4
+RETURNING id, created_at, updated_at, email, chirpy_red, handle;
 
 -- name: UpdateUserChirpyRed :one
 UPDATE users
@@ -36,3 +37,100 @@ RETURNING id, created_at, updated_at, email, chirpy_red;
 SELECT *
 FROM users
 WHERE id = $1;
+
+-- name: UpdateUserHandle :exec
+UPDATE users
+SET handle = $1,
+    updated_at = NOW()
+WHERE id = $2;
+
+-- name: GetUsersByHandles :many
+SELECT id, handle
+FROM users
+WHERE handle = ANY(sqlc.arg('handles')::text[]);
+
+-- name: UpdateUserRole :one
+UPDATE users
+SET role = $1,
+    updated_at = NOW()
+WHERE id = $2
+RETURNING id, created_at, updated_at, email, chirpy_red, handle, role;
+
+-- name: CountUsersWithRole :one
+SELECT COUNT(*)
+FROM users
+WHERE role = $1;
+
+-- name: PromoteUserByEmail :one
+UPDATE users
+SET role = sqlc.arg('role'),
+    updated_at = NOW()
+WHERE email = sqlc.arg('email')
+RETURNING id, created_at, updated_at, email, chirpy_red, handle, role;
+
+-- name: GetUserAccountStatus :one
+SELECT
+    role,
+    (suspended_at IS NOT NULL AND (suspended_until IS NULL OR suspended_until > NOW()))::boolean AS suspended,
+    suspension_reason,
+    suspended_until,
+    password_reset_required,
+    (email_verified_at IS NOT NULL)::boolean AS email_verified
+FROM users
+WHERE id = $1;
+
+-- name: ListUsers :many
+SELECT *
+FROM users
+WHERE (
+    sqlc.narg('query')::text IS NULL
+    OR email ILIKE '%' || sqlc.narg('query')::text || '%'
+    OR handle ILIKE '%' || sqlc.narg('query')::text || '%'
+  )
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: SuspendUser :execrows
+UPDATE users
+SET suspended_at = NOW(),
+    suspension_reason = sqlc.arg('reason'),
+    suspended_until = CASE
+        WHEN sqlc.narg('duration_seconds')::int IS NULL THEN NULL
+        ELSE NOW() + make_interval(secs => sqlc.narg('duration_seconds')::int)
+    END,
+    updated_at = NOW()
+WHERE id = sqlc.arg('id');
+
+-- name: UnsuspendUser :execrows
+UPDATE users
+SET suspended_at = NULL,
+    suspension_reason = '',
+    suspended_until = NULL,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: RequirePasswordReset :execrows
+UPDATE users
+SET password_reset_required = TRUE,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateUserPassword :one
+UPDATE users
+SET hashed_password = $1,
+    password_reset_required = FALSE,
+    updated_at = NOW()
+WHERE id = $2
+RETURNING *;
+
+-- name: ConfirmUserEmail :one
+UPDATE users
+SET email = $1,
+    email_verified_at = NOW(),
+    updated_at = NOW()
+WHERE id = $2
+RETURNING *;
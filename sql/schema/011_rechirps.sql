@@ -0,0 +1,24 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN quote_of UUID REFERENCES chirps (id) ON DELETE CASCADE;
+
+CREATE INDEX chirps_quote_of_idx ON chirps (quote_of);
+
+CREATE TABLE rechirps (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    chirp_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (chirp_id) REFERENCES chirps (id) ON DELETE CASCADE,
+    CONSTRAINT rechirps_user_id_chirp_id_key UNIQUE (user_id, chirp_id)
+);
+
+CREATE INDEX rechirps_user_id_created_at_id_idx ON rechirps (user_id, created_at, id);
+CREATE INDEX rechirps_chirp_id_idx ON rechirps (chirp_id);
+
+-- +goose Down
+DROP TABLE rechirps;
+
+ALTER TABLE chirps
+DROP COLUMN quote_of;
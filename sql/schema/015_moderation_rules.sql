@@ -0,0 +1,30 @@
+-- +goose Up
+CREATE TABLE moderation_rules (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    pattern TEXT NOT NULL,
+    match_type TEXT NOT NULL CHECK (match_type IN ('word', 'substring')),
+    action TEXT NOT NULL CHECK (action IN ('mask', 'reject', 'flag')),
+    replacement TEXT NOT NULL DEFAULT ''
+);
+
+INSERT INTO moderation_rules (id, created_at, updated_at, pattern, match_type, action)
+VALUES
+    (gen_random_uuid(), NOW(), NOW(), 'kerfuffle', 'word', 'mask'),
+    (gen_random_uuid(), NOW(), NOW(), 'sharbert', 'word', 'mask'),
+    (gen_random_uuid(), NOW(), NOW(), 'fornax', 'word', 'mask');
+
+CREATE TABLE chirp_flags (
+    id UUID PRIMARY KEY,
+    chirp_id UUID NOT NULL,
+    pattern TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (chirp_id) REFERENCES chirps (id) ON DELETE CASCADE
+);
+
+CREATE INDEX chirp_flags_chirp_id_idx ON chirp_flags (chirp_id);
+
+-- +goose Down
+DROP TABLE chirp_flags;
+DROP TABLE moderation_rules;
@@ -0,0 +1,59 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN hidden_at TIMESTAMP;
+
+CREATE TABLE moderation_decisions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    chirp_id UUID NOT NULL,
+    chirp_user_id UUID NOT NULL,
+    chirp_body TEXT NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('dismiss', 'hide', 'unhide', 'remove')),
+    note TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX moderation_decisions_created_at_id_idx ON moderation_decisions (created_at, id);
+
+CREATE TABLE reports (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    chirp_id UUID NOT NULL,
+    reporter_id UUID,
+    reason TEXT NOT NULL CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'sexual', 'self_harm', 'misinformation', 'other', 'flagged')),
+    details TEXT NOT NULL DEFAULT '',
+    resolved_at TIMESTAMP,
+    decision_id UUID,
+    UNIQUE (chirp_id, reporter_id),
+    FOREIGN KEY (chirp_id) REFERENCES chirps (id) ON DELETE CASCADE,
+    FOREIGN KEY (reporter_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (decision_id) REFERENCES moderation_decisions (id) ON DELETE SET NULL
+);
+
+CREATE INDEX reports_open_created_at_idx ON reports (created_at) WHERE resolved_at IS NULL;
+
+-- Chirps flagged by the moderation dictionary now go through the same
+-- queue as user reports, with no reporter.
+INSERT INTO reports (id, created_at, chirp_id, reporter_id, reason, details)
+SELECT id, created_at, chirp_id, NULL, 'flagged', pattern
+FROM chirp_flags;
+
+DROP TABLE chirp_flags;
+
+-- +goose Down
+CREATE TABLE chirp_flags (
+    id UUID PRIMARY KEY,
+    chirp_id UUID NOT NULL,
+    pattern TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (chirp_id) REFERENCES chirps (id) ON DELETE CASCADE
+);
+
+CREATE INDEX chirp_flags_chirp_id_idx ON chirp_flags (chirp_id);
+
+INSERT INTO chirp_flags (id, chirp_id, pattern, created_at)
+SELECT id, chirp_id, details, created_at
+FROM reports
+WHERE reason = 'flagged';
+
+DROP TABLE reports;
+DROP TABLE moderation_decisions;
+ALTER TABLE chirps DROP COLUMN hidden_at;
@@ -0,0 +1,37 @@
+-- +goose Up
+CREATE TABLE user_blocks (
+    blocker_id UUID NOT NULL,
+    blocked_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (blocker_id, blocked_id),
+    FOREIGN KEY (blocker_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (blocked_id) REFERENCES users (id) ON DELETE CASCADE,
+    CHECK (blocker_id <> blocked_id)
+);
+
+CREATE INDEX user_blocks_blocked_id_idx ON user_blocks (blocked_id);
+
+CREATE TABLE user_mutes (
+    muter_id UUID NOT NULL,
+    muted_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (muter_id, muted_id),
+    FOREIGN KEY (muter_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (muted_id) REFERENCES users (id) ON DELETE CASCADE,
+    CHECK (muter_id <> muted_id)
+);
+
+-- viewer_hidden_authors lists, for each viewer, the authors whose chirps
+-- they should not see: everyone they muted and everyone on either side of
+-- a block with them.
+CREATE VIEW viewer_hidden_authors AS
+SELECT muter_id AS viewer_id, muted_id AS author_id FROM user_mutes
+UNION
+SELECT blocker_id, blocked_id FROM user_blocks
+UNION
+SELECT blocked_id, blocker_id FROM user_blocks;
+
+-- +goose Down
+DROP VIEW viewer_hidden_authors;
+DROP TABLE user_mutes;
+DROP TABLE user_blocks;
@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN suspended_at TIMESTAMP,
+ADD COLUMN password_reset_required BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX users_created_at_id_idx ON users (created_at, id);
+
+-- +goose Down
+DROP INDEX users_created_at_id_idx;
+ALTER TABLE users
+DROP COLUMN password_reset_required,
+DROP COLUMN suspended_at;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- A suspension without suspended_until is a permanent ban.
+ALTER TABLE users
+ADD COLUMN suspension_reason TEXT NOT NULL DEFAULT '',
+ADD COLUMN suspended_until TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN suspended_until,
+DROP COLUMN suspension_reason;
@@ -0,0 +1,28 @@
+-- +goose Up
+-- login_failures counts recent failed logins per subject, which is either
+-- "email:<address>" or "ip:<address>". Subjects for unknown emails are
+-- tracked the same way so lockouts don't reveal which accounts exist.
+CREATE TABLE login_failures (
+    subject TEXT PRIMARY KEY,
+    failures INTEGER NOT NULL,
+    last_failed_at TIMESTAMP NOT NULL,
+    blocked_until TIMESTAMP
+);
+
+CREATE TABLE login_lockouts (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('account', 'ip')),
+    email TEXT NOT NULL,
+    user_id UUID REFERENCES users (id) ON DELETE SET NULL,
+    ip TEXT NOT NULL,
+    failures INTEGER NOT NULL,
+    locked_until TIMESTAMP NOT NULL
+);
+
+CREATE INDEX login_lockouts_created_at_idx ON login_lockouts (created_at, id);
+CREATE INDEX login_lockouts_user_id_idx ON login_lockouts (user_id);
+
+-- +goose Down
+DROP TABLE login_lockouts;
+DROP TABLE login_failures;
@@ -0,0 +1,30 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN email_verified_at TIMESTAMP;
+
+-- Accounts created before verification existed were never sent a token,
+-- so they are treated as verified rather than locked out of chirping.
+UPDATE users
+SET email_verified_at = created_at
+WHERE email_verified_at IS NULL;
+
+-- A verification token proves ownership of email. When email differs from
+-- the user's current address the token is a pending email change, applied
+-- once the token is used.
+CREATE TABLE email_verification_tokens (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX email_verification_tokens_user_id_idx ON email_verification_tokens (user_id);
+
+-- +goose Down
+DROP TABLE email_verification_tokens;
+
+ALTER TABLE users
+DROP COLUMN email_verified_at;
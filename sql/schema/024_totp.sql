@@ -0,0 +1,40 @@
+-- +goose Up
+-- totp_pending_secret holds a secret during enrollment, until the user
+-- confirms it with a first code. totp_last_step is the time step of the
+-- last accepted code, so codes can't be replayed.
+ALTER TABLE users
+ADD COLUMN totp_secret TEXT,
+ADD COLUMN totp_pending_secret TEXT,
+ADD COLUMN totp_enabled_at TIMESTAMP,
+ADD COLUMN totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE totp_recovery_codes (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMP,
+    UNIQUE (user_id, code_hash)
+);
+
+-- A login challenge is issued after a correct password when the account
+-- has two-factor authentication, and is exchanged for tokens with a code.
+CREATE TABLE login_challenges (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    used_at TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE login_challenges;
+DROP TABLE totp_recovery_codes;
+
+ALTER TABLE users
+DROP COLUMN totp_last_step,
+DROP COLUMN totp_enabled_at,
+DROP COLUMN totp_pending_secret,
+DROP COLUMN totp_secret;
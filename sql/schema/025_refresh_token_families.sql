@@ -0,0 +1,20 @@
+-- +goose Up
+-- Refresh tokens are rotated on use. Every token descends from one login,
+-- its family; rotated_at marks tokens that were exchanged for a newer one.
+ALTER TABLE refresh_tokens
+ADD COLUMN family_id UUID,
+ADD COLUMN rotated_at TIMESTAMP;
+
+UPDATE refresh_tokens SET family_id = gen_random_uuid();
+
+ALTER TABLE refresh_tokens
+ALTER COLUMN family_id SET NOT NULL;
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+
+-- +goose Down
+DROP INDEX refresh_tokens_family_id_idx;
+
+ALTER TABLE refresh_tokens
+DROP COLUMN rotated_at,
+DROP COLUMN family_id;
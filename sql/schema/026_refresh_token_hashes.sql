@@ -0,0 +1,17 @@
+-- +goose Up
+-- Refresh tokens are stored as the hex encoded SHA-256 digest of the
+-- token, so the table no longer holds usable bearer values.
+ALTER TABLE refresh_tokens
+RENAME COLUMN token TO token_hash;
+
+UPDATE refresh_tokens
+SET token_hash = encode(sha256(convert_to(token_hash, 'UTF8')), 'hex');
+
+-- +goose Down
+-- Digests can't be turned back into tokens, so every session ends.
+UPDATE refresh_tokens
+SET revoked_at = NOW()
+WHERE revoked_at IS NULL;
+
+ALTER TABLE refresh_tokens
+RENAME COLUMN token_hash TO token;
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/UUest/gohttp/internal/database"
+)
+
+// getChirpThread returns the whole conversation a chirp belongs to, starting
+// from the root chirp, with replies nested under the chirp they answer.
+func (cfg *apiConfig) getChirpThread(w http.ResponseWriter, r *http.Request) {
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, nil)
+		return
+	}
+	rootID, err := cfg.dbQueries.GetChirpThreadRootID(r.Context(), chirpUUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, nil)
+		return
+	}
+	if err != nil {
+		log.Printf("failed to get thread root: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	// Replies from authors the viewer muted or shares a block with are
+	// dropped along with the replies beneath them.
+	rows, err := cfg.dbQueries.GetChirpThread(r.Context(), database.GetChirpThreadParams{
+		ChirpID:  rootID,
+		ViewerID: cfg.viewerID(r),
+	})
+	if err != nil {
+		log.Printf("failed to get chirp thread: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	if len(rows) == 0 {
+		respondWithError(w, http.StatusNotFound, nil)
+		return
+	}
+
+	type threadNode struct {
+		Id          uuid.UUID     `json:"id"`
+		Body        string        `json:"body"`
+		Created_at  time.Time     `json:"created_at"`
+		Updated_at  time.Time     `json:"updated_at"`
+		User_id     uuid.UUID     `json:"user_id"`
+		In_reply_to *uuid.UUID    `json:"in_reply_to,omitempty"`
+		Depth       int32         `json:"depth"`
+		Reply_count int           `json:"reply_count"`
+		Replies     []*threadNode `json:"replies"`
+	}
+	// Rows come back ordered by depth, so every parent is in the map before
+	// any of its replies.
+	nodes := make(map[uuid.UUID]*threadNode, len(rows))
+	var root *threadNode
+	for _, row := range rows {
+		node := &threadNode{
+			Id:          row.ID,
+			Body:        row.Body,
+			Created_at:  row.CreatedAt,
+			Updated_at:  row.UpdatedAt,
+			User_id:     row.UserID,
+			In_reply_to: nullUUIDPtr(row.InReplyTo),
+			Depth:       row.Depth,
+			Replies:     []*threadNode{},
+		}
+		nodes[row.ID] = node
+		if row.Depth == 0 {
+			root = node
+			continue
+		}
+		parent, ok := nodes[row.InReplyTo.UUID]
+		if !ok {
+			continue
+		}
+		parent.Replies = append(parent.Replies, node)
+		parent.Reply_count++
+	}
+	dat, err := json.Marshal(root)
+	if err != nil {
+		log.Printf("failed to marshal response body: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, dat)
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/UUest/gohttp/internal/auth"
+	"github.com/UUest/gohttp/internal/database"
+	"github.com/UUest/gohttp/internal/totp"
+)
+
+const (
+	totpIssuer = "Chirpy"
+	// loginChallengeTTL is how long a user has to enter their second factor
+	// after a correct password.
+	loginChallengeTTL         = 5 * time.Minute
+	maxLoginChallengeAttempts = 5
+	recoveryCodeCount         = 10
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// makeRecoveryCodes returns n random codes formatted as "xxxxx-xxxxx".
+func makeRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	for range n {
+		key := make([]byte, 7)
+		_, err := rand.Read(key)
+		if err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(key))[:10]
+		codes = append(codes, code[:5]+"-"+code[5:])
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode hashes a recovery code as typed by a user, ignoring
+// case, spaces and dashes.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	return auth.HashToken(code)
+}
+
+// checkSecondFactor reports whether code is a valid TOTP code or unused
+// recovery code for user. Accepted TOTP codes and recovery codes can't be
+// used again.
+func (cfg *apiConfig) checkSecondFactor(ctx context.Context, user database.User, code, recoveryCode string) (bool, error) {
+	if recoveryCode != "" {
+		n, err := cfg.dbQueries.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+			UserID:   user.ID,
+			CodeHash: hashRecoveryCode(recoveryCode),
+		})
+		return n == 1, err
+	}
+	step, ok := totp.Validate(user.TotpSecret.String, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	n, err := cfg.dbQueries.UseTOTPStep(ctx, database.UseTOTPStepParams{
+		Step: step,
+		ID:   user.ID,
+	})
+	return n == 1, err
+}
+
+// respondWithLoginChallenge answers a correct password for an account with
+// two-factor authentication. Instead of tokens it returns a challenge
+// token to exchange, with a code, at POST /api/login/2fa.
+func (cfg *apiConfig) respondWithLoginChallenge(w http.ResponseWriter, r *http.Request, user database.User) {
+	token, err := auth.MakeToken()
+	if err != nil {
+		log.Printf("failed to make challenge token: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	err = cfg.dbQueries.CreateLoginChallenge(r.Context(), database.CreateLoginChallengeParams{
+		UserID:     user.ID,
+		TokenHash:  auth.HashToken(token),
+		TtlSeconds: int32(loginChallengeTTL.Seconds()),
+	})
+	if err != nil {
+		log.Printf("failed to create login challenge: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	type resParameters struct {
+		Two_factor_required bool   `json:"two_factor_required"`
+		Challenge_token     string `json:"challenge_token"`
+		Expires_in          int    `json:"expires_in"`
+	}
+	dat, err := json.Marshal(resParameters{
+		Two_factor_required: true,
+		Challenge_token:     token,
+		Expires_in:          int(loginChallengeTTL.Seconds()),
+	})
+	if err != nil {
+		log.Printf("failed to marshal response body: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, dat)
+}
+
+// loginSecondFactor completes a two-step login. Wrong codes count as
+// failed logins for the account.
+func (cfg *apiConfig) loginSecondFactor(w http.ResponseWriter, r *http.Request) {
+	type reqParameters struct {
+		Challenge_token string `json:"challenge_token"`
+		Code            string `json:"code"`
+		Recovery_code   string `json:"recovery_code"`
+	}
+	reqParams := reqParameters{}
+	err := json.NewDecoder(r.Body).Decode(&reqParams)
+	if err != nil || reqParams.Challenge_token == "" || (reqParams.Code == "") == (reqParams.Recovery_code == "") {
+		respondWithError(w, http.StatusBadRequest, []byte("challenge_token and either code or recovery_code are required"))
+		return
+	}
+	challenge, err := cfg.dbQueries.AttemptLoginChallenge(r.Context(), database.AttemptLoginChallengeParams{
+		TokenHash:   auth.HashToken(reqParams.Challenge_token),
+		MaxAttempts: maxLoginChallengeAttempts,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusUnauthorized, []byte("invalid or expired challenge, log in again"))
+		return
+	}
+	if err != nil {
+		log.Printf("failed to attempt login challenge: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), challenge.UserID)
+	if err != nil {
+		log.Printf("failed to get user by id: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	if !cfg.checkLoginThrottle(w, r, user.Email) {
+		return
+	}
+	_, err = cfg.checkAccount(r.Context(), user.ID)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		ok, err := cfg.checkSecondFactor(r.Context(), user, reqParams.Code, reqParams.Recovery_code)
+		if err != nil {
+			log.Printf("failed to check second factor: %s", err)
+			respondWithError(w, http.StatusInternalServerError, nil)
+			return
+		}
+		if !ok {
+			cfg.recordLoginFailure(r.Context(), accountLoginThrottle, accountLoginSubject(user.Email), user.Email, cfg.clientIP(r), uuid.NullUUID{UUID: user.ID, Valid: true})
+			respondWithError(w, http.StatusUnauthorized, []byte("invalid code"))
+			return
+		}
+	}
+	n, err := cfg.dbQueries.CompleteLoginChallenge(r.Context(), challenge.ID)
+	if err != nil {
+		log.Printf("failed to complete login challenge: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusUnauthorized, []byte("invalid or expired challenge, log in again"))
+		return
+	}
+	cfg.respondWithLogin(w, r, user)
+}
+
+func (cfg *apiConfig) getTwoFactorStatus(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		log.Printf("failed to get user by id: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	remaining, err := cfg.dbQueries.CountUnusedRecoveryCodes(r.Context(), userID)
+	if err != nil {
+		log.Printf("failed to count recovery codes: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	type resParameters struct {
+		Totp_enabled             bool       `json:"totp_enabled"`
+		Totp_enabled_at          *time.Time `json:"totp_enabled_at,omitempty"`
+		Recovery_codes_remaining int64      `json:"recovery_codes_remaining"`
+	}
+	dat, err := json.Marshal(resParameters{
+		Totp_enabled:             user.TotpEnabledAt.Valid,
+		Totp_enabled_at:          nullTimePtr(user.TotpEnabledAt),
+		Recovery_codes_remaining: remaining,
+	})
+	if err != nil {
+		log.Printf("failed to marshal response body: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, dat)
+}
+
+// enrollTOTP starts TOTP enrollment with a new secret. Two-factor
+// authentication is only turned on once confirmTOTP sees a code made
+// from it.
+func (cfg *apiConfig) enrollTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		log.Printf("failed to get user by id: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		respondWithError(w, http.StatusConflict, []byte("two-factor authentication is already enabled"))
+		return
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		log.Printf("failed to generate TOTP secret: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	err = cfg.dbQueries.SetTOTPPendingSecret(r.Context(), database.SetTOTPPendingSecretParams{
+		TotpPendingSecret: sql.NullString{String: secret, Valid: true},
+		ID:                userID,
+	})
+	if err != nil {
+		log.Printf("failed to set pending TOTP secret: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	type resParameters struct {
+		Secret           string `json:"secret"`
+		Provisioning_uri string `json:"provisioning_uri"`
+	}
+	dat, err := json.Marshal(resParameters{
+		Secret:           secret,
+		Provisioning_uri: totp.ProvisioningURI(totpIssuer, user.Email, secret),
+	})
+	if err != nil {
+		log.Printf("failed to marshal response body: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, dat)
+}
+
+// confirmTOTP turns two-factor authentication on and returns the recovery
+// codes. They are only shown this once.
+func (cfg *apiConfig) confirmTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	type reqParameters struct {
+		Code string `json:"code"`
+	}
+	reqParams := reqParameters{}
+	err = json.NewDecoder(r.Body).Decode(&reqParams)
+	if err != nil {
+		log.Printf("failed to decode request body: %s", err)
+		respondWithError(w, http.StatusBadRequest, nil)
+		return
+	}
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		log.Printf("failed to get user by id: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	if !user.TotpPendingSecret.Valid {
+		respondWithError(w, http.StatusConflict, []byte("no TOTP enrollment in progress"))
+		return
+	}
+	step, ok := totp.Validate(user.TotpPendingSecret.String, reqParams.Code, time.Now())
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, []byte("invalid code"))
+		return
+	}
+	codes, err := makeRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		log.Printf("failed to make recovery codes: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("failed to begin transaction: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+	n, err := qtx.EnableTOTP(r.Context(), database.EnableTOTPParams{
+		Step:   step,
+		ID:     userID,
+		Secret: user.TotpPendingSecret,
+	})
+	if err != nil {
+		log.Printf("failed to enable TOTP: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusConflict, []byte("TOTP enrollment changed, enroll again"))
+		return
+	}
+	err = qtx.DeleteRecoveryCodes(r.Context(), userID)
+	if err != nil {
+		log.Printf("failed to delete recovery codes: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	for _, code := range codes {
+		err = qtx.CreateRecoveryCode(r.Context(), database.CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: hashRecoveryCode(code),
+		})
+		if err != nil {
+			log.Printf("failed to create recovery code: %s", err)
+			respondWithError(w, http.StatusInternalServerError, nil)
+			return
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("failed to commit TOTP enrollment: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	type resParameters struct {
+		Recovery_codes []string `json:"recovery_codes"`
+	}
+	dat, err := json.Marshal(resParameters{Recovery_codes: codes})
+	if err != nil {
+		log.Printf("failed to marshal response body: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, dat)
+}
+
+// disableTOTP turns two-factor authentication off. It takes a current code
+// or a recovery code, so a stolen access token alone can't do it.
+func (cfg *apiConfig) disableTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	type reqParameters struct {
+		Code          string `json:"code"`
+		Recovery_code string `json:"recovery_code"`
+	}
+	reqParams := reqParameters{}
+	err = json.NewDecoder(r.Body).Decode(&reqParams)
+	if err != nil || (reqParams.Code == "") == (reqParams.Recovery_code == "") {
+		respondWithError(w, http.StatusBadRequest, []byte("either code or recovery_code is required"))
+		return
+	}
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		log.Printf("failed to get user by id: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	if !user.TotpEnabledAt.Valid {
+		respondWithError(w, http.StatusConflict, []byte("two-factor authentication is not enabled"))
+		return
+	}
+	ok, err := cfg.checkSecondFactor(r.Context(), user, reqParams.Code, reqParams.Recovery_code)
+	if err != nil {
+		log.Printf("failed to check second factor: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, []byte("invalid code"))
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("failed to begin transaction: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+	err = qtx.DisableTOTP(r.Context(), userID)
+	if err != nil {
+		log.Printf("failed to disable TOTP: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	err = qtx.DeleteRecoveryCodes(r.Context(), userID)
+	if err != nil {
+		log.Printf("failed to delete recovery codes: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("failed to commit TOTP removal: %s", err)
+		respondWithError(w, http.StatusInternalServerError, nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}